@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// passwordEnv supplies the account password to runs without a terminal.
+// It is read directly rather than through the generic TARTARUS_* flag mapping, so it never
+// shows up as the value of --password.
+const passwordEnv = "TARTARUS_PASSWORD"
+
+// errNoTerminal explains how to supply credentials when there is nobody to prompt.
+var errNoTerminal = errors.New("stdin is not a terminal; use --password-file, --password-stdin or " + passwordEnv)
+
+// stdinIsTerminal reports whether we can prompt the user.
+func stdinIsTerminal() bool {
+	return term.IsTerminal(int(syscall.Stdin))
+}
+
+// readPasswordFrom reads a password from r, up to the first newline.
+func readPasswordFrom(r io.Reader) (string, error) {
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		return "", errors.New("password is empty")
+	}
+	return password, nil
+}
+
+// suppliedPassword returns the password given with --password, --password-file, --password-stdin
+// or TARTARUS_PASSWORD, or "" if there is none and the caller should prompt.
+// The result is kept in args.Password, so stdin is only read once even if the session is renewed.
+func suppliedPassword(args *AuthArgs) (string, error) {
+	given := 0
+	for _, set := range []bool{args.Password != "", args.PasswordFile != "", args.PasswordStdin} {
+		if set {
+			given++
+		}
+	}
+	if given > 1 {
+		return "", errors.New("use only one of --password, --password-file and --password-stdin")
+	}
+
+	switch {
+	case args.Password != "":
+		return args.Password, nil
+	case args.PasswordFile != "":
+		f, err := os.Open(args.PasswordFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read password file: %w", err)
+		}
+		defer f.Close()
+		password, err := readPasswordFrom(f)
+		if err != nil {
+			return "", fmt.Errorf("failed to read password file %s: %w", args.PasswordFile, err)
+		}
+		args.Password, args.PasswordFile = password, ""
+		return password, nil
+	case args.PasswordStdin:
+		if args.Email == "" {
+			return "", errors.New("--password-stdin requires --email, since stdin can't be used for both")
+		}
+		password, err := readPasswordFrom(os.Stdin)
+		if err != nil {
+			return "", fmt.Errorf("failed to read password from stdin: %w", err)
+		}
+		args.Password, args.PasswordStdin = password, false
+		return password, nil
+	}
+	return os.Getenv(passwordEnv), nil
+}
+
+// decodeJWTClaims decodes the payload of a JWT into claims without verifying its signature.
+func decodeJWTClaims(token string, claims interface{}) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return fmt.Errorf("malformed JWT: expected 3 segments, got %d", len(parts))
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return fmt.Errorf("failed to decode JWT payload: %w", err)
+	}
+	if err := json.Unmarshal(payload, claims); err != nil {
+		return fmt.Errorf("failed to parse JWT claims: %w", err)
+	}
+	return nil
+}
+
+// newServiceKeySession returns a session that authenticates every request with a Supabase
+// service-role or secret API key. Nothing is cached, refreshed or prompted for.
+// The key is sent as both the apikey and the bearer token, so it replaces the anon key too.
+func newServiceKeySession(args *AuthArgs) *authSession {
+	s := &authSession{
+		args:        args,
+		AccessToken: args.ServiceRoleKey,
+		UserID:      args.UserID,
+		serviceKey:  true,
+	}
+	args.SupabaseAnonKey = args.ServiceRoleKey
+
+	// Legacy service-role keys are JWTs; the newer sb_secret_ keys are opaque.
+	var claims struct {
+		Sub string      `json:"sub"`
+		Exp json.Number `json:"exp"`
+	}
+	if decodeJWTClaims(args.ServiceRoleKey, &claims) == nil {
+		if s.UserID == "" {
+			s.UserID = claims.Sub
+		}
+		if exp, err := claims.Exp.Int64(); err == nil {
+			s.ExpiresAt = time.Unix(exp, 0)
+		}
+	}
+	fmt.Fprintln(os.Stderr, "Using the service-role key; the token cache is not used.")
+	return s
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+const (
+	logoutEndpointPath = "/auth/v1/logout"
+	userEndpointPath   = "/auth/v1/user"
+)
+
+// --- Auth Command Functionality ---
+
+// LoginArgs defines the arguments for the 'auth login' subcommand.
+type LoginArgs struct {
+	HardwareProviderID int `cli:"--hp-id, Hardware Provider ID to store in the profile (optional)"`
+	AuthArgs
+}
+
+// WhoamiArgs defines the arguments for the 'auth whoami' subcommand.
+type WhoamiArgs struct {
+	JSON bool `cli:"--json, Print the identity as JSON"`
+	AuthArgs
+}
+
+// whoamiInfo is what 'auth whoami' reports.
+type whoamiInfo struct {
+	Profile            string `json:"profile"`
+	UserID             string `json:"user_id"`
+	Email              string `json:"email,omitempty"`
+	TokenExpiresAt     string `json:"token_expires_at,omitempty"`
+	HardwareProviderID int    `json:"hardware_provider_id,omitempty"`
+	SupabaseURL        string `json:"supabase_url"`
+	CredentialStore    string `json:"credential_store"`
+	expiresAt          time.Time
+}
+
+// parseAuthArgs parses the command line into args and applies the selected profile.
+func parseAuthArgs(command string, args interface{}, authArgs *AuthArgs) profile {
+	fs := parseArgs(command, args)
+	prof, err := applyProfile(fs, authArgs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading profile: %v\n", err)
+		os.Exit(1)
+	}
+	return prof
+}
+
+// requireCachedSession loads the profile's session without prompting, refreshing it if needed.
+// It is used by commands that must work unattended, so a missing login is an error.
+func requireCachedSession(args *AuthArgs) (*authSession, error) {
+	if args.ServiceRoleKey != "" {
+		return newServiceKeySession(args), nil
+	}
+
+	store, err := newCredentialStore(args.CredentialStore)
+	if err != nil {
+		return nil, err
+	}
+
+	s := loadCachedSession(args, store)
+	if s == nil {
+		return nil, fmt.Errorf("not logged in for profile %q, run 'tartarus auth login --profile %s' first", args.Profile, args.Profile)
+	}
+	if s.needsRefresh() {
+		if s.RefreshToken == "" {
+			return nil, fmt.Errorf("access token for profile %q has expired, run 'tartarus auth login --profile %s' again", args.Profile, args.Profile)
+		}
+		if err := s.refresh(); err != nil {
+			return nil, fmt.Errorf("access token for profile %q has expired and could not be refreshed: %w", args.Profile, err)
+		}
+	}
+	return s, nil
+}
+
+// login authenticates with the profile's settings, caches the session in store and records
+// the hardware provider ID in the profile.
+func login(args *LoginArgs, store credentialStore) (*authSession, error) {
+	session, err := requestAccessToken(&args.AuthArgs, store)
+	if err != nil {
+		return nil, err
+	}
+	if args.HardwareProviderID != 0 {
+		if err := updateProfile(args.Profile, func(p *profile) { p.HardwareProviderID = args.HardwareProviderID }); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to update profile %q: %v\n", args.Profile, err)
+		}
+	}
+	return session, nil
+}
+
+// runAuthLoginCommand is the handler for the "auth login" subcommand.
+func runAuthLoginCommand() {
+	var loginArgs LoginArgs
+	parseAuthArgs("auth login", &loginArgs, &loginArgs.AuthArgs)
+
+	store, err := newCredentialStore(loginArgs.CredentialStore)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	session, err := login(&loginArgs, store)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error logging in: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Logged in as user %s (profile %q).\n", session.UserID, loginArgs.Profile)
+	if !session.ExpiresAt.IsZero() {
+		fmt.Printf("Access token expires at %s.\n", session.ExpiresAt.Format(time.RFC3339))
+	}
+}
+
+// logout revokes the profile's session server-side and then deletes the cached token.
+// It reports false if the profile was not logged in.
+func logout(args *AuthArgs, store credentialStore) (bool, error) {
+	session := loadCachedSession(args, store)
+	if session == nil {
+		return false, nil
+	}
+
+	// An expired token can't be revoked with itself; the refresh token is what keeps the session alive.
+	if session.needsRefresh() && session.RefreshToken != "" {
+		if err := session.refresh(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to refresh token before logout: %v\n", err)
+		}
+	}
+
+	if err := revokeSession(args, session.AccessToken); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to revoke the session on the server: %v\n", err)
+	} else {
+		fmt.Println("Session revoked.")
+	}
+
+	if err := store.Delete(args.Profile); err != nil {
+		return true, fmt.Errorf("deleting cached token: %w", err)
+	}
+	return true, nil
+}
+
+// runAuthLogoutCommand is the handler for the "auth logout" subcommand.
+func runAuthLogoutCommand() {
+	var logoutArgs AuthArgs
+	parseAuthArgs("auth logout", &logoutArgs, &logoutArgs)
+
+	store, err := newCredentialStore(logoutArgs.CredentialStore)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	loggedIn, err := logout(&logoutArgs, store)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if !loggedIn {
+		fmt.Printf("Profile %q is not logged in.\n", logoutArgs.Profile)
+		return
+	}
+	fmt.Printf("Removed cached token for profile %q from %s.\n", logoutArgs.Profile, store.Location(logoutArgs.Profile))
+}
+
+// revokeSession calls the Supabase logout endpoint, which invalidates the refresh token.
+func revokeSession(args *AuthArgs, accessToken string) error {
+	req, err := http.NewRequest("POST", args.SupabaseURL+logoutEndpointPath, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create logout request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("apikey", args.SupabaseAnonKey)
+
+	client := &http.Client{Timeout: authRequestTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("logout request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// 401 means the session is already gone, which is what we wanted anyway.
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusUnauthorized {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("logout failed. Status: %s, Body: %s", resp.Status, string(respBody))
+	}
+	return nil
+}
+
+// fetchUser asks Supabase who the access token belongs to.
+func fetchUser(args *AuthArgs, accessToken string) (id string, email string, err error) {
+	req, err := http.NewRequest("GET", args.SupabaseURL+userEndpointPath, nil)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create user request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("apikey", args.SupabaseAnonKey)
+
+	client := &http.Client{Timeout: authRequestTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", "", fmt.Errorf("user request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read user response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("user request failed. Status: %s, Body: %s", resp.Status, string(respBody))
+	}
+
+	var user struct {
+		ID    string `json:"id"`
+		Email string `json:"email"`
+	}
+	if err := json.Unmarshal(respBody, &user); err != nil {
+		return "", "", fmt.Errorf("failed to parse user response JSON: %w. Body: %s", err, string(respBody))
+	}
+	return user.ID, user.Email, nil
+}
+
+// whoami verifies the profile's cached token against the server and reports who it belongs to.
+func whoami(args *WhoamiArgs, prof profile) (whoamiInfo, error) {
+	session, err := requireCachedSession(&args.AuthArgs)
+	if err != nil {
+		return whoamiInfo{}, err
+	}
+
+	info := whoamiInfo{
+		Profile:            args.Profile,
+		HardwareProviderID: prof.HardwareProviderID,
+		SupabaseURL:        args.SupabaseURL,
+		expiresAt:          session.ExpiresAt,
+	}
+	if session.store == nil {
+		// A service-role key has no user behind it, so it is checked against the nodes table instead.
+		if _, err := newRestClient(session).fetchNodeRows(nil, 1, 1); err != nil {
+			return whoamiInfo{}, fmt.Errorf("verifying service role key: %w", err)
+		}
+		info.UserID = session.UserID
+		info.CredentialStore = "service role key (not stored)"
+	} else {
+		userID, email, err := fetchUser(&args.AuthArgs, session.AccessToken)
+		if err != nil {
+			return whoamiInfo{}, fmt.Errorf("verifying access token: %w", err)
+		}
+		info.UserID, info.Email = userID, email
+		info.CredentialStore = session.store.Location(args.Profile)
+	}
+	if !session.ExpiresAt.IsZero() {
+		info.TokenExpiresAt = session.ExpiresAt.Format(time.RFC3339)
+	}
+	return info, nil
+}
+
+// runAuthWhoamiCommand is the handler for the "auth whoami" subcommand.
+// It verifies the cached token against the server, so a zero exit status means the token works.
+func runAuthWhoamiCommand() {
+	var whoamiArgs WhoamiArgs
+	prof := parseAuthArgs("auth whoami", &whoamiArgs, &whoamiArgs.AuthArgs)
+
+	info, err := whoami(&whoamiArgs, prof)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if whoamiArgs.JSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(info); err != nil {
+			fmt.Fprintf(os.Stderr, "Error encoding JSON: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	fmt.Printf("Profile:            %s\n", info.Profile)
+	fmt.Printf("User ID:            %s\n", info.UserID)
+	if info.Email != "" {
+		fmt.Printf("Email:              %s\n", info.Email)
+	}
+	if info.TokenExpiresAt != "" {
+		fmt.Printf("Token expires at:   %s (in %s)\n", info.TokenExpiresAt, time.Until(info.expiresAt).Round(time.Second))
+	} else {
+		fmt.Printf("Token expires at:   unknown\n")
+	}
+	if info.HardwareProviderID != 0 {
+		fmt.Printf("Hardware provider:  %d\n", info.HardwareProviderID)
+	} else {
+		fmt.Printf("Hardware provider:  not configured (pass --hp-id to 'auth login' to store it)\n")
+	}
+	fmt.Printf("Supabase URL:       %s\n", info.SupabaseURL)
+	fmt.Printf("Credential store:   %s\n", info.CredentialStore)
+}
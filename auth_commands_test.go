@@ -0,0 +1,56 @@
+package main
+
+import "testing"
+
+func TestLoginWhoamiLogout(t *testing.T) {
+	dev, srv, userID := startDevServer(t)
+	args := devUploadArgs(srv, "").AuthArgs
+	store := fileStore{}
+
+	session, err := login(&LoginArgs{HardwareProviderID: 7, AuthArgs: args}, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if session.UserID != userID {
+		t.Fatalf("expected user %s, got %s", userID, session.UserID)
+	}
+	cached, err := store.Load(args.Profile)
+	if err != nil || cached == nil || cached.AccessToken != session.AccessToken {
+		t.Fatalf("login did not cache the session: %+v, %v", cached, err)
+	}
+
+	// whoami must work from the cache alone, without a password.
+	whoamiArgs := &WhoamiArgs{AuthArgs: args}
+	whoamiArgs.Password = ""
+	prof, err := applyProfile(nil, &whoamiArgs.AuthArgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := whoami(whoamiArgs, prof)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.UserID != userID || info.Email != "dev@example.com" || info.HardwareProviderID != 7 || info.CredentialStore != store.Location(args.Profile) {
+		t.Fatalf("unexpected whoami info %+v", info)
+	}
+	if got := dev.Requests("POST", "/auth/v1/token"); got != 1 {
+		t.Errorf("expected whoami to reuse the cached token, got %d token requests", got)
+	}
+
+	loggedIn, err := logout(&args, store)
+	if err != nil || !loggedIn {
+		t.Fatalf("logout failed: %v, %v", loggedIn, err)
+	}
+	if cached, err := store.Load(args.Profile); err != nil || cached != nil {
+		t.Fatalf("logout left a cached token: %+v, %v", cached, err)
+	}
+	if got := dev.Requests("POST", logoutEndpointPath); got != 1 {
+		t.Errorf("expected the session to be revoked, got %d logout requests", got)
+	}
+	if _, err := whoami(whoamiArgs, prof); err == nil {
+		t.Fatal("expected whoami to fail after logout")
+	}
+	if loggedIn, err := logout(&args, store); err != nil || loggedIn {
+		t.Fatalf("expected a second logout to find no session: %v, %v", loggedIn, err)
+	}
+}
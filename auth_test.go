@@ -0,0 +1,220 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testJWT builds an unsigned JWT with the given exp claim.
+func testJWT(exp time.Time) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+	payload := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"sub":"user-1","exp":%d}`, exp.Unix())))
+	return header + "." + payload + ".sig"
+}
+
+// isolateConfig points the XDG config dir at a fresh temporary directory for the duration of the test.
+func isolateConfig(t *testing.T) {
+	t.Helper()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+}
+
+func TestJWTExpiry(t *testing.T) {
+	want := time.Unix(1900000000, 0)
+	got, err := jwtExpiry(testJWT(want))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+
+	if _, err := jwtExpiry("not-a-jwt"); err == nil {
+		t.Fatal("expected an error for a malformed token")
+	}
+}
+
+func TestGetOrRequestAccessTokenRefreshesExpiredCache(t *testing.T) {
+	isolateConfig(t)
+
+	freshToken := testJWT(time.Now().Add(time.Hour))
+	var gotRefreshToken string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("grant_type") != "refresh_token" {
+			t.Errorf("unexpected grant_type %q", r.URL.Query().Get("grant_type"))
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		gotRefreshToken = body["refresh_token"]
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token":  freshToken,
+			"refresh_token": "refresh-2",
+			"user":          map[string]string{"id": "user-1"},
+		})
+	}))
+	defer srv.Close()
+
+	args := &AuthArgs{SupabaseURL: srv.URL, Profile: defaultProfileName, CredentialStore: credentialStoreFile}
+	expired := &authSession{
+		args:         args,
+		store:        fileStore{},
+		AccessToken:  testJWT(time.Now().Add(-time.Minute)),
+		RefreshToken: "refresh-1",
+		UserID:       "user-1",
+	}
+	expired.save()
+
+	session, err := getOrRequestAccessToken(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotRefreshToken != "refresh-1" {
+		t.Fatalf("expected refresh with cached refresh token, got %q", gotRefreshToken)
+	}
+	if session.AccessToken != freshToken || session.RefreshToken != "refresh-2" {
+		t.Fatalf("session was not updated from the refresh response: %+v", session)
+	}
+
+	cached := loadCachedSession(args, fileStore{})
+	if cached == nil || cached.AccessToken != freshToken {
+		t.Fatal("refreshed token was not written back to the cache")
+	}
+}
+
+func TestEncryptedFileStoreRoundTrip(t *testing.T) {
+	isolateConfig(t)
+	t.Setenv(credentialsPassphraseEnv, "correct horse battery staple")
+
+	token := &cachedToken{AccessToken: "a", RefreshToken: "r", UserID: "u", ExpiresAt: 42}
+	if err := (&encryptedFileStore{}).Save("fuji-test", token); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := (&encryptedFileStore{}).Load("fuji-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got == nil || *got != *token {
+		t.Fatalf("expected %+v, got %+v", token, got)
+	}
+
+	wrong := &encryptedFileStore{passphrase: []byte("wrong")}
+	if _, err := wrong.Load("fuji-test"); err == nil {
+		t.Fatal("expected decryption with the wrong passphrase to fail")
+	}
+}
+
+func TestCheckPasswordStrength(t *testing.T) {
+	cases := []struct {
+		password string
+		ok       bool
+	}{
+		{"short1A!", false},
+		{"alllowercaseletters", false},
+		{"Correct-Horse-42", true},
+		{"Alice-Secret-2024", false}, // contains the email name
+	}
+	for _, c := range cases {
+		err := checkPasswordStrength(c.password, "alice@example.com")
+		if (err == nil) != c.ok {
+			t.Errorf("%q: expected ok=%v, got err=%v", c.password, c.ok, err)
+		}
+	}
+}
+
+func TestSuppliedPassword(t *testing.T) {
+	t.Setenv(passwordEnv, "from-env")
+
+	file := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(file, []byte("from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	args := &AuthArgs{PasswordFile: file}
+	if got, err := suppliedPassword(args); err != nil || got != "from-file" {
+		t.Fatalf("expected the password file to win over the environment, got %q, %v", got, err)
+	}
+	// The file is read once; renewals reuse the value.
+	os.Remove(file)
+	if got, err := suppliedPassword(args); err != nil || got != "from-file" {
+		t.Fatalf("expected the password to be kept after the first read, got %q, %v", got, err)
+	}
+
+	if got, err := suppliedPassword(&AuthArgs{}); err != nil || got != "from-env" {
+		t.Fatalf("expected %s to be used, got %q, %v", passwordEnv, got, err)
+	}
+
+	if _, err := suppliedPassword(&AuthArgs{Password: "a", PasswordFile: file}); err == nil {
+		t.Fatal("expected an error when more than one password source is given")
+	}
+	if _, err := suppliedPassword(&AuthArgs{PasswordStdin: true}); err == nil {
+		t.Fatal("expected --password-stdin without --email to be rejected")
+	}
+}
+
+func TestServiceKeySession(t *testing.T) {
+	key := testJWT(time.Now().Add(time.Hour))
+	args := &AuthArgs{ServiceRoleKey: key, SupabaseAnonKey: "anon"}
+
+	session, err := getOrRequestAccessToken(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if session.AccessToken != key || args.SupabaseAnonKey != key {
+		t.Fatal("expected the service key to be used as both the bearer token and the apikey")
+	}
+	if session.UserID != "user-1" {
+		t.Fatalf("expected the user ID from the sub claim, got %q", session.UserID)
+	}
+	if err := session.Renew(); err == nil {
+		t.Fatal("expected renewing a service key session to fail")
+	}
+
+	session = newServiceKeySession(&AuthArgs{ServiceRoleKey: "sb_secret_opaque", UserID: "owner"})
+	if session.UserID != "owner" || !session.ExpiresAt.IsZero() {
+		t.Fatalf("unexpected session for an opaque key: %+v", session)
+	}
+
+	// whoami has no credential store to report and no user to ask the auth API about.
+	dev, srv, _ := startDevServer(t)
+	whoamiArgs := &WhoamiArgs{AuthArgs: AuthArgs{ServiceRoleKey: dev.ServiceKey(), UserID: "owner", SupabaseURL: srv.URL, Profile: defaultProfileName}}
+	info, err := whoami(whoamiArgs, profile{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.UserID != "owner" || info.CredentialStore != "service role key (not stored)" {
+		t.Fatalf("unexpected whoami info for a service key: %+v", info)
+	}
+	whoamiArgs = &WhoamiArgs{AuthArgs: AuthArgs{ServiceRoleKey: "wrong", SupabaseURL: srv.URL, Profile: defaultProfileName}}
+	if _, err := whoami(whoamiArgs, profile{}); err == nil {
+		t.Fatal("expected whoami to reject an invalid service key")
+	}
+}
+
+func TestProfileNameValidation(t *testing.T) {
+	isolateConfig(t)
+
+	for _, name := range []string{"default", "fuji-test", "dev_2"} {
+		if err := validateProfileName(name); err != nil {
+			t.Errorf("%q: unexpected error %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "../../x", "a/b", `a\b`, "..", "prod.bak"} {
+		if _, err := credentialsPath(name, ".json"); err == nil {
+			t.Errorf("%q: expected the name to be rejected", name)
+		}
+		if _, err := applyProfile(nil, &AuthArgs{Profile: name}); name != "" && err == nil {
+			t.Errorf("%q: expected applyProfile to reject the name", name)
+		}
+	}
+	if err := saveProfile("../escape", profile{}); err == nil {
+		t.Fatal("expected saveProfile to reject a name with a path separator")
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/multisig-labs/tartarus/node"
+)
+
+// BLSNewArgs defines the arguments for the 'bls new' subcommand.
+type BLSNewArgs struct {
+	Output     string `cli:"-o, --output, File to write the raw 32-byte signer key to" default:"signer.key"`
+	StakingDir string `cli:"-d, --staking-dir, Write signer.key into this staking directory, next to its staker.crt, instead of --output"`
+	Force      bool   `cli:"--force, Replace an existing signer key; the old one is kept as <file>.rotated-<timestamp>"`
+	Format     string `cli:"-f, --format, Encoding of the printed keys: 0x or hex" default:"0x"`
+	JSON       bool   `cli:"--json, Print the NodeID, public key and proof of possession as JSON"`
+}
+
+// BLSKeyArgs defines the arguments for the 'bls pop' and 'bls pubkey' subcommands.
+type BLSKeyArgs struct {
+	Key        string `cli:"-k, --key, Raw 32-byte signer key to read" default:"signer.key"`
+	StakingDir string `cli:"-d, --staking-dir, Read signer.key from this staking directory instead of --key"`
+	Format     string `cli:"-f, --format, Encoding of the printed keys: 0x or hex" default:"0x"`
+	JSON       bool   `cli:"--json, Print JSON instead of text"`
+}
+
+// blsPOP is the shape avalanchego's info.getNodeID uses for a node's BLS key.
+type blsPOP struct {
+	PublicKey         string `json:"publicKey"`
+	ProofOfPossession string `json:"proofOfPossession,omitempty"`
+}
+
+// encodeKeyHex encodes b as plain hex or with a 0x prefix.
+func encodeKeyHex(b []byte, format string) string {
+	if format == "hex" {
+		return hex.EncodeToString(b)
+	}
+	return "0x" + hex.EncodeToString(b)
+}
+
+func checkKeyFormat(format string) {
+	if format != "0x" && format != "hex" {
+		fmt.Fprintf(os.Stderr, "Error: unsupported format %q (expected 0x or hex)\n", format)
+		os.Exit(1)
+	}
+}
+
+// stakingDirNodeID returns the NodeID of the staker.crt in dir.
+func stakingDirNodeID(dir string) (string, error) {
+	certPEM, err := os.ReadFile(filepath.Join(dir, node.StakerCertFile))
+	if err != nil {
+		return "", err
+	}
+	nodeID, err := node.NodeIDFromCertPEM(string(certPEM))
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", filepath.Join(dir, node.StakerCertFile), err)
+	}
+	return nodeID, nil
+}
+
+// printBLSKey prints the public key and proof of possession, and the NodeID if it is known.
+func printBLSKey(w io.Writer, key node.BLSKey, nodeID, format string, asJSON bool) error {
+	pop := blsPOP{
+		PublicKey:         encodeKeyHex(key.PublicKey, format),
+		ProofOfPossession: encodeKeyHex(key.ProofOfPossession, format),
+	}
+	if asJSON {
+		out := struct {
+			NodeID  string `json:"nodeID,omitempty"`
+			NodePOP blsPOP `json:"nodePOP"`
+		}{nodeID, pop}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(out)
+	}
+	if nodeID != "" {
+		fmt.Fprintf(w, "NodeID:              %s\n", nodeID)
+	}
+	fmt.Fprintf(w, "Public key:          %s\n", pop.PublicKey)
+	_, err := fmt.Fprintf(w, "Proof of possession: %s\n", pop.ProofOfPossession)
+	return err
+}
+
+// runBLSNewCommand is the handler for the "bls new" subcommand.
+func runBLSNewCommand() {
+	var newArgs BLSNewArgs
+	parseArgs("bls new", &newArgs)
+	checkKeyFormat(newArgs.Format)
+
+	path, nodeID := newArgs.Output, ""
+	if newArgs.StakingDir != "" {
+		var err error
+		if nodeID, err = stakingDirNodeID(newArgs.StakingDir); err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading the staking cert: %v\n", err)
+			os.Exit(1)
+		}
+		path = filepath.Join(newArgs.StakingDir, node.SignerKeyFile)
+	}
+
+	info, err := os.Stat(path)
+	exists := err == nil
+	switch {
+	case err != nil && !errors.Is(err, fs.ErrNotExist):
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	case exists && !newArgs.Force:
+		fmt.Fprintf(os.Stderr, "Error: %s already exists. Use --force to replace it, or rotate-bls to rotate keys in bulk.\n", path)
+		os.Exit(1)
+	}
+
+	key, err := node.NewBLSKey(rand.Reader)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error generating BLS key: %v\n", err)
+		os.Exit(1)
+	}
+
+	if exists {
+		archive := archiveName(path, time.Now())
+		if err := archiveFile(path, archive, info.Mode().Perm()); err != nil {
+			fmt.Fprintf(os.Stderr, "Error archiving %s: %v\n", path, err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "The old key was kept as %s\n", archive)
+	}
+	// The secret key gives full control of the node's BLS identity, so keep it owner-only.
+	if err := writeFileAtomic(path, key.Secret, 0600); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", path, err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "BLS signer key saved to %s\n", path)
+
+	if err := printBLSKey(os.Stdout, key, nodeID, newArgs.Format, newArgs.JSON); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// readBLSKeyArgs loads the key named by --key or --staking-dir, and the NodeID if a staking dir was given.
+func readBLSKeyArgs(args *BLSKeyArgs) (node.BLSKey, string) {
+	checkKeyFormat(args.Format)
+	path, nodeID := args.Key, ""
+	if args.StakingDir != "" {
+		path = filepath.Join(args.StakingDir, node.SignerKeyFile)
+		// The NodeID is informational, so a missing cert is not an error.
+		if id, err := stakingDirNodeID(args.StakingDir); err == nil {
+			nodeID = id
+		}
+	}
+	key, err := node.ReadSignerKey(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading signer key: %v\n", err)
+		os.Exit(1)
+	}
+	return key, nodeID
+}
+
+// runBLSPopCommand is the handler for the "bls pop" subcommand.
+func runBLSPopCommand() {
+	var popArgs BLSKeyArgs
+	parseArgs("bls pop", &popArgs)
+	key, nodeID := readBLSKeyArgs(&popArgs)
+
+	if err := printBLSKey(os.Stdout, key, nodeID, popArgs.Format, popArgs.JSON); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// runBLSPubkeyCommand is the handler for the "bls pubkey" subcommand. It prints only the public key.
+func runBLSPubkeyCommand() {
+	var pubkeyArgs BLSKeyArgs
+	parseArgs("bls pubkey", &pubkeyArgs)
+	key, _ := readBLSKeyArgs(&pubkeyArgs)
+
+	if pubkeyArgs.JSON {
+		data, _ := json.Marshal(blsPOP{PublicKey: encodeKeyHex(key.PublicKey, pubkeyArgs.Format)})
+		fmt.Println(string(data))
+		return
+	}
+	fmt.Println(encodeKeyHex(key.PublicKey, pubkeyArgs.Format))
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jxskiss/mcli"
+	"github.com/multisig-labs/tartarus/models"
+	"github.com/multisig-labs/tartarus/node"
+)
+
+// infoAPIPath is where avalanchego serves the info API.
+const infoAPIPath = "/ext/info"
+
+// CheckNodeArgs defines the arguments for the 'check-node' subcommand.
+type CheckNodeArgs struct {
+	DataFile  string        `cli:"-d, --data-file, Nodes file (JSON, NDJSON or CSV) or a staking directories tree with the expected keys"`
+	Endpoints []string      `cli:"-e, --endpoint, Base URL of a node's API, e.g. http://127.0.0.1:9650 (repeatable)"`
+	Timeout   time.Duration `cli:"--timeout, Timeout for each request" default:"10s"`
+	JSON      bool          `cli:"--json, Print the results as JSON"`
+}
+
+// nodeCheck is the result of checking one running node.
+type nodeCheck struct {
+	Endpoint     string   `json:"endpoint"`
+	NodeID       string   `json:"node_id,omitempty"`
+	BLSPublicKey string   `json:"bls_public_key,omitempty"`
+	OK           bool     `json:"ok"`
+	Problems     []string `json:"problems,omitempty"`
+}
+
+// getNodeIDResult is the result of avalanchego's info.getNodeID.
+type getNodeIDResult struct {
+	NodeID  string `json:"nodeID"`
+	NodePOP blsPOP `json:"nodePOP"`
+}
+
+// callInfoAPI makes a JSON-RPC call to the info API at endpoint and decodes the result.
+func callInfoAPI(client *http.Client, endpoint, method string, result interface{}) error {
+	body, _ := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "method": method, "params": map[string]interface{}{}})
+	resp, err := client.Post(strings.TrimSuffix(endpoint, "/")+infoAPIPath, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %d: %s", method, resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	var rpcResp struct {
+		Result json.RawMessage `json:"result"`
+		Error  *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(respBody, &rpcResp); err != nil {
+		return fmt.Errorf("invalid JSON-RPC response: %w", err)
+	}
+	if rpcResp.Error != nil {
+		return fmt.Errorf("%s failed: %s (code %d)", method, rpcResp.Error.Message, rpcResp.Error.Code)
+	}
+	if err := json.Unmarshal(rpcResp.Result, result); err != nil {
+		return fmt.Errorf("invalid %s result: %w", method, err)
+	}
+	return nil
+}
+
+// checkNode compares what the node at endpoint reports with the expected nodes, keyed by NodeID.
+func checkNode(client *http.Client, endpoint string, expected map[string]models.Node) nodeCheck {
+	check := nodeCheck{Endpoint: endpoint}
+	var got getNodeIDResult
+	if err := callInfoAPI(client, endpoint, "info.getNodeID", &got); err != nil {
+		check.Problems = append(check.Problems, err.Error())
+		return check
+	}
+	check.NodeID, check.BLSPublicKey = got.NodeID, got.NodePOP.PublicKey
+
+	want, ok := expected[got.NodeID]
+	if !ok {
+		check.Problems = append(check.Problems, fmt.Sprintf("%s is not in the data file; the wrong staker.crt and staker.key may be deployed", got.NodeID))
+		return check
+	}
+	switch {
+	case got.NodePOP.PublicKey == "":
+		check.Problems = append(check.Problems, "the node reports no BLS key; signer.key may be missing")
+	case normalizeHex(got.NodePOP.PublicKey) != normalizeHex(want.BLSPublicKey):
+		check.Problems = append(check.Problems, fmt.Sprintf("BLS public key %s does not match the expected %s; the wrong signer.key is deployed", got.NodePOP.PublicKey, with0x(want.BLSPublicKey)))
+	default:
+		if err := node.VerifyProofOfPossession(got.NodePOP.PublicKey, got.NodePOP.ProofOfPossession); err != nil {
+			check.Problems = append(check.Problems, fmt.Sprintf("the node's proof of possession is invalid: %v", err))
+		}
+	}
+	check.OK = len(check.Problems) == 0
+	return check
+}
+
+// runCheckNodeCommand is the handler for the "check-node" subcommand.
+// It exits non-zero if any node is unreachable or runs with keys other than the expected ones.
+func runCheckNodeCommand() {
+	var checkArgs CheckNodeArgs
+	parseArgs("check-node", &checkArgs)
+	if checkArgs.DataFile == "" || len(checkArgs.Endpoints) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: --data-file and --endpoint are required.")
+		mcli.PrintHelp()
+		os.Exit(1)
+	}
+
+	nodes, err := readNodesFile(checkArgs.DataFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	expected := make(map[string]models.Node, len(nodes))
+	for _, n := range nodes {
+		expected[n.NodeID] = n
+	}
+
+	client := &http.Client{Timeout: checkArgs.Timeout}
+	checks := make([]nodeCheck, 0, len(checkArgs.Endpoints))
+	failed := 0
+	for _, endpoint := range checkArgs.Endpoints {
+		check := checkNode(client, endpoint, expected)
+		if !check.OK {
+			failed++
+		}
+		checks = append(checks, check)
+	}
+
+	if checkArgs.JSON {
+		if err := writeJSONOutput("", checks); err != nil {
+			fmt.Fprintf(os.Stderr, "Error encoding JSON: %v\n", err)
+			os.Exit(1)
+		}
+	} else {
+		for _, c := range checks {
+			status := "OK"
+			if !c.OK {
+				status = "FAIL"
+			}
+			fmt.Printf("%-4s %s %s\n", status, c.Endpoint, c.NodeID)
+			for _, p := range c.Problems {
+				fmt.Printf("     - %s\n", p)
+			}
+		}
+	}
+	if failed > 0 {
+		fmt.Fprintf(os.Stderr, "%d of %d nodes failed the check.\n", failed, len(checks))
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/multisig-labs/tartarus/models"
+)
+
+// fakeInfoAPI stands in for avalanchego's info API, answering info.getNodeID as n.
+func fakeInfoAPI(t *testing.T, n models.Node) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Method string `json:"method"`
+		}
+		if r.URL.Path != infoAPIPath || json.NewDecoder(r.Body).Decode(&req) != nil {
+			http.NotFound(w, r)
+			return
+		}
+		if req.Method != "info.getNodeID" {
+			json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "error": map[string]interface{}{"code": -32601, "message": "method not found"}})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "result": getNodeIDResult{
+			NodeID:  n.NodeID,
+			NodePOP: blsPOP{PublicKey: with0x(n.BLSPublicKey), ProofOfPossession: with0x(n.BLSSignature)},
+		}})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestCheckNode(t *testing.T) {
+	nodes := generateNodes(t, 3)
+	expected := map[string]models.Node{nodes[0].NodeID: nodes[0], nodes[1].NodeID: nodes[1]}
+	client := http.DefaultClient
+
+	if c := checkNode(client, fakeInfoAPI(t, nodes[0]).URL, expected); !c.OK || c.NodeID != nodes[0].NodeID {
+		t.Fatalf("matching node failed the check: %+v", c)
+	}
+
+	// The staking cert of node 1 with the signer.key of node 2.
+	wrongSigner := nodes[1]
+	wrongSigner.BLSPublicKey, wrongSigner.BLSSignature = nodes[2].BLSPublicKey, nodes[2].BLSSignature
+	c := checkNode(client, fakeInfoAPI(t, wrongSigner).URL, expected)
+	if c.OK || len(c.Problems) != 1 || !strings.Contains(c.Problems[0], "wrong signer.key") {
+		t.Fatalf("wrong signer.key not flagged: %+v", c)
+	}
+
+	c = checkNode(client, fakeInfoAPI(t, nodes[2]).URL, expected)
+	if c.OK || !strings.Contains(c.Problems[0], "not in the data file") {
+		t.Fatalf("unknown NodeID not flagged: %+v", c)
+	}
+
+	srv := fakeInfoAPI(t, nodes[0])
+	srv.Close()
+	if c := checkNode(client, srv.URL, expected); c.OK || len(c.Problems) == 0 {
+		t.Fatalf("unreachable node passed the check: %+v", c)
+	}
+}
@@ -0,0 +1,351 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/BurntSushi/toml"
+	"github.com/jxskiss/mcli"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// configEnvPrefix prefixes the environment variable of every flag: --hp-id is TARTARUS_HP_ID.
+	configEnvPrefix = "TARTARUS_"
+	// configPathEnv points at a config file explicitly, skipping the search.
+	configPathEnv = "TARTARUS_CONFIG"
+)
+
+// configFileNames are looked for in the working directory and its parents, then in the XDG config dir.
+var configFileNames = []string{"tartarus.yaml", "tartarus.yml", "tartarus.toml"}
+
+// unconfigurableFlags are never read from the environment or a config file.
+// The password has its own TARTARUS_PASSWORD variable, see suppliedPassword.
+var unconfigurableFlags = map[string]bool{"password": true}
+
+// secretFlags are read from the environment but never from a config file, which tends to get
+// committed, and are masked by 'config show'.
+var secretFlags = map[string]bool{"service-role-key": true}
+
+// Sources of a resolved setting, as shown by 'config show'.
+const (
+	sourceFlag    = "flag"
+	sourceDefault = "default"
+	sourceProfile = "profile"
+)
+
+// fileConfig is a parsed tartarus.yaml or tartarus.toml. Top-level keys apply to every command;
+// a table named after a command (e.g. upload or "sync push") overrides them for that command.
+type fileConfig struct {
+	Path   string
+	Values map[string]interface{}
+}
+
+// findConfigFile returns the config file to use, or "" if there is none.
+func findConfigFile() (string, error) {
+	if path := os.Getenv(configPathEnv); path != "" {
+		if _, err := os.Stat(path); err != nil {
+			return "", fmt.Errorf("%s: %w", configPathEnv, err)
+		}
+		return path, nil
+	}
+
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	for {
+		for _, name := range configFileNames {
+			path := filepath.Join(dir, name)
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				return path, nil
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+
+	if dir, err := configDir(); err == nil {
+		for _, name := range configFileNames {
+			path := filepath.Join(dir, name)
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				return path, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// loadConfigFile finds and parses the config file. It returns nil if there is none.
+func loadConfigFile() (*fileConfig, error) {
+	path, err := findConfigFile()
+	if err != nil || path == "" {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	values := map[string]interface{}{}
+	if strings.HasSuffix(path, ".toml") {
+		err = toml.Unmarshal(data, &values)
+	} else {
+		err = yaml.Unmarshal(data, &values)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return &fileConfig{Path: path, Values: values}, nil
+}
+
+// lookup returns the value of a flag for command. Keys may be written with dashes or underscores.
+func (c *fileConfig) lookup(command, name string) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	keys := []string{name, strings.ReplaceAll(name, "-", "_")}
+	if section, ok := c.Values[command].(map[string]interface{}); ok {
+		for _, k := range keys {
+			if v, ok := section[k]; ok {
+				return configString(v), true
+			}
+		}
+	}
+	for _, k := range keys {
+		if v, ok := c.Values[k]; ok {
+			if _, isSection := v.(map[string]interface{}); !isSection {
+				return configString(v), true
+			}
+		}
+	}
+	return "", false
+}
+
+func configString(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+// configEnvName returns the environment variable for a flag.
+func configEnvName(flagName string) string {
+	return configEnvPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// applyConfig fills every flag that was not given on the command line from its TARTARUS_*
+// environment variable or the config file, in that order. It returns where each value came from.
+// Values applied here count as set for applyProfile, so both take precedence over the profile.
+func applyConfig(fs *flag.FlagSet, command string) (map[string]string, error) {
+	sources := map[string]string{}
+	fs.Visit(func(f *flag.Flag) { sources[f.Name] = sourceFlag })
+
+	cfg, err := loadConfigFile()
+	if err != nil {
+		return nil, err
+	}
+
+	var setErr error
+	fs.VisitAll(func(f *flag.Flag) {
+		// Short aliases share the value of their long flag.
+		if setErr != nil || len(f.Name) == 1 || unconfigurableFlags[f.Name] || sources[f.Name] != "" {
+			return
+		}
+		source := ""
+		value, ok := os.LookupEnv(configEnvName(f.Name))
+		if ok && value != "" {
+			source = "env " + configEnvName(f.Name)
+		} else if value, ok = cfg.lookup(command, f.Name); ok && !secretFlags[f.Name] {
+			source = "config " + cfg.Path
+		} else {
+			sources[f.Name] = sourceDefault
+			return
+		}
+		if err := fs.Set(f.Name, value); err != nil {
+			setErr = fmt.Errorf("invalid value %q for --%s from %s: %w", value, f.Name, source, err)
+			return
+		}
+		sources[f.Name] = source
+	})
+	return sources, setErr
+}
+
+// parseArgs parses the command line into args and applies the environment and config file on top.
+func parseArgs(command string, args interface{}) *flag.FlagSet {
+	fs, parseErr := mcli.Parse(args)
+	if parseErr != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing %s command arguments: %v\n", command, parseErr)
+		os.Exit(1)
+	}
+	if _, err := applyConfig(fs, command); err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading configuration: %v\n", err)
+		os.Exit(1)
+	}
+	return fs
+}
+
+// configurableCommands returns a fresh args struct for every command 'config show' can resolve,
+// with the embedded AuthArgs if the command has them.
+var configurableCommands = map[string]func() (interface{}, *AuthArgs){
+	"generate":                     func() (interface{}, *AuthArgs) { return &GenerateArgs{}, nil },
+	"convert":                      func() (interface{}, *AuthArgs) { return &ConvertArgs{}, nil },
+	"rotate-bls":                   func() (interface{}, *AuthArgs) { return &RotateBLSArgs{}, nil },
+	"bls new":                      func() (interface{}, *AuthArgs) { return &BLSNewArgs{}, nil },
+	"bls pop":                      func() (interface{}, *AuthArgs) { return &BLSKeyArgs{}, nil },
+	"bls pubkey":                   func() (interface{}, *AuthArgs) { return &BLSKeyArgs{}, nil },
+	"check-node":                   func() (interface{}, *AuthArgs) { return &CheckNodeArgs{}, nil },
+	"export":                       func() (interface{}, *AuthArgs) { return &ExportArgs{}, nil },
+	"inspect":                      func() (interface{}, *AuthArgs) { return &InspectArgs{}, nil },
+	"pchain add-validator":         func() (interface{}, *AuthArgs) { return &PChainAddValidatorArgs{}, nil },
+	"pchain l1-validators":         func() (interface{}, *AuthArgs) { return &PChainL1ValidatorsArgs{}, nil },
+	"pchain register-l1-validator": func() (interface{}, *AuthArgs) { return &PChainRegisterL1ValidatorArgs{}, nil },
+	"upload":                       func() (interface{}, *AuthArgs) { a := &UploadArgs{}; return a, &a.AuthArgs },
+	"sync push":                    func() (interface{}, *AuthArgs) { a := &UploadArgs{}; return a, &a.AuthArgs },
+	"sync diff":                    func() (interface{}, *AuthArgs) { a := &SyncDiffArgs{}; return a, &a.AuthArgs },
+	"nodes list":                   func() (interface{}, *AuthArgs) { a := &NodesListArgs{}; return a, &a.AuthArgs },
+	"nodes show":                   func() (interface{}, *AuthArgs) { a := &NodesShowArgs{}; return a, &a.AuthArgs },
+	"nodes set-status":             func() (interface{}, *AuthArgs) { a := &NodesSetStatusArgs{}; return a, &a.AuthArgs },
+	"nodes remove":                 func() (interface{}, *AuthArgs) { a := &NodesRemoveArgs{}; return a, &a.AuthArgs },
+	"nodes fetch-secrets":          func() (interface{}, *AuthArgs) { a := &NodesFetchSecretsArgs{}; return a, &a.AuthArgs },
+	"auth login":                   func() (interface{}, *AuthArgs) { a := &LoginArgs{}; return a, &a.AuthArgs },
+	"auth logout":                  func() (interface{}, *AuthArgs) { a := &AuthArgs{}; return a, a },
+	"auth whoami":                  func() (interface{}, *AuthArgs) { a := &WhoamiArgs{}; return a, &a.AuthArgs },
+	"auth signup":                  func() (interface{}, *AuthArgs) { a := &AuthArgs{}; return a, a },
+	"auth resend-verification":     func() (interface{}, *AuthArgs) { a := &AuthArgs{}; return a, a },
+	"auth reset-password":          func() (interface{}, *AuthArgs) { a := &ResetPasswordArgs{}; return a, &a.AuthArgs },
+	"dev-server":                   func() (interface{}, *AuthArgs) { return &DevServerArgs{}, nil },
+}
+
+// ConfigShowArgs defines the arguments for the 'config show' subcommand.
+type ConfigShowArgs struct {
+	Command []string `cli:"command, Command to resolve the settings of (e.g. upload, sync push)"`
+}
+
+// resolvedSetting is one row of 'config show'.
+type resolvedSetting struct {
+	Name, Value, Source string
+}
+
+// resolveCommandSettings parses command with no arguments, so only the environment, config file,
+// profile and defaults apply, and reports the value and source of every flag.
+func resolveCommandSettings(command string) ([]resolvedSetting, error) {
+	newArgs, ok := configurableCommands[command]
+	if !ok {
+		return nil, fmt.Errorf("unknown command %q", command)
+	}
+	args, authArgs := newArgs()
+
+	var settings []resolvedSetting
+	var resolveErr error
+	app := mcli.NewApp()
+	app.Add(command, func(ctx *mcli.Context) {
+		fs, err := ctx.Parse(args, mcli.WithErrorHandling(flag.ContinueOnError))
+		if err != nil {
+			resolveErr = err
+			return
+		}
+		sources, err := applyConfig(fs, command)
+		if err != nil {
+			resolveErr = err
+			return
+		}
+
+		if authArgs != nil {
+			before := map[string]string{}
+			fs.VisitAll(func(f *flag.Flag) { before[f.Name] = f.Value.String() })
+			prof, err := applyProfile(fs, authArgs)
+			if err != nil {
+				resolveErr = err
+				return
+			}
+			// upload and sync push also take these from the profile.
+			if up, ok := args.(*UploadArgs); ok {
+				if up.HardwareProviderID == 0 {
+					up.HardwareProviderID = prof.HardwareProviderID
+				}
+				if up.SecretsRecipient == "" {
+					up.SecretsRecipient = prof.SecretsRecipient
+				}
+			}
+			fs.VisitAll(func(f *flag.Flag) {
+				if f.Value.String() != before[f.Name] {
+					sources[f.Name] = sourceProfile + " " + authArgs.Profile
+				}
+			})
+		}
+
+		fs.VisitAll(func(f *flag.Flag) {
+			if len(f.Name) == 1 || unconfigurableFlags[f.Name] {
+				return
+			}
+			settings = append(settings, resolvedSetting{Name: f.Name, Value: f.Value.String(), Source: sources[f.Name]})
+		})
+	}, "")
+	app.Run(strings.Fields(command)...)
+
+	sort.Slice(settings, func(i, j int) bool { return settings[i].Name < settings[j].Name })
+	return settings, resolveErr
+}
+
+// runConfigShowCommand is the handler for the "config show" subcommand.
+func runConfigShowCommand() {
+	var showArgs ConfigShowArgs
+	if _, err := mcli.Parse(&showArgs); err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing config show command arguments: %v\n", err)
+		os.Exit(1)
+	}
+	command := strings.Join(showArgs.Command, " ")
+	if command == "" {
+		command = "upload"
+	}
+
+	cfg, err := loadConfigFile()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading configuration: %v\n", err)
+		os.Exit(1)
+	}
+	settings, err := resolveCommandSettings(command)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		names := make([]string, 0, len(configurableCommands))
+		for name := range configurableCommands {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fmt.Fprintf(os.Stderr, "Error: %v (expected one of: %s)\n", err, strings.Join(names, ", "))
+		os.Exit(1)
+	}
+
+	if cfg != nil {
+		fmt.Printf("Config file: %s\n", cfg.Path)
+	} else {
+		fmt.Println("Config file: none")
+	}
+	fmt.Printf("Settings for '%s':\n\n", command)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SETTING\tVALUE\tSOURCE")
+	for _, s := range settings {
+		value := s.Value
+		if secretFlags[s.Name] && value != "" {
+			value = "(set)"
+		}
+		// Keep long values such as the anon key from pushing the source column off screen.
+		if len(value) > 64 {
+			value = value[:40] + "..." + value[len(value)-12:]
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", s.Name, value, s.Source)
+	}
+	w.Flush()
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestApplyConfigPrecedence(t *testing.T) {
+	isolateConfig(t)
+	root := t.TempDir()
+	work := filepath.Join(root, "fleet", "batch-1")
+	if err := os.MkdirAll(work, 0755); err != nil {
+		t.Fatal(err)
+	}
+	config := `
+network = "fuji"
+hp_id = 7
+l1-id = "from-config"
+batch-size = 10
+
+[upload]
+batch-size = 50
+`
+	if err := os.WriteFile(filepath.Join(root, "fleet", "tartarus.toml"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	wd, _ := os.Getwd()
+	if err := os.Chdir(work); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	t.Setenv("TARTARUS_L1_ID", "from-env")
+	t.Setenv("TARTARUS_NETWORK", "from-env")
+	t.Setenv("TARTARUS_PASSWORD", "never-read-here")
+
+	fs := flag.NewFlagSet("upload", flag.ContinueOnError)
+	network := fs.String("network", "mainnet", "")
+	hpID := fs.Int("hp-id", 0, "")
+	l1ID := fs.String("l1-id", "", "")
+	batchSize := fs.Int("batch-size", 25, "")
+	retries := fs.Int("retries", 3, "")
+	password := fs.String("password", "", "")
+	if err := fs.Parse([]string{"--network", "from-flag"}); err != nil {
+		t.Fatal(err)
+	}
+
+	sources, err := applyConfig(fs, "upload")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	configPath := filepath.Join(root, "fleet", "tartarus.toml")
+	for _, c := range []struct {
+		name, got, want, source string
+	}{
+		{"network", *network, "from-flag", sourceFlag},
+		{"l1-id", *l1ID, "from-env", "env TARTARUS_L1_ID"},
+		{"hp-id", strconv.Itoa(*hpID), "7", "config " + configPath},
+		{"batch-size", strconv.Itoa(*batchSize), "50", "config " + configPath},
+		{"retries", strconv.Itoa(*retries), "3", sourceDefault},
+	} {
+		if c.got != c.want {
+			t.Errorf("%s: got %q, expected %q", c.name, c.got, c.want)
+		}
+		if sources[c.name] != c.source {
+			t.Errorf("%s: source %q, expected %q", c.name, sources[c.name], c.source)
+		}
+	}
+	if *password != "" {
+		t.Error("the password must not be taken from the environment or config file")
+	}
+}
+
+func TestResolveEveryConfigurableCommand(t *testing.T) {
+	isolateConfig(t)
+	t.Setenv(configPathEnv, "")
+	t.Setenv("TARTARUS_FORMAT", "json")
+
+	for command := range configurableCommands {
+		settings, err := resolveCommandSettings(command)
+		if err != nil {
+			t.Errorf("%s: %v", command, err)
+			continue
+		}
+		if len(settings) == 0 {
+			t.Errorf("%s: no settings resolved", command)
+		}
+	}
+
+	// nodes show was once left out, so its flags ignored the environment.
+	settings, err := resolveCommandSettings("nodes show")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range settings {
+		if s.Name == "format" && (s.Value != "json" || s.Source != "env TARTARUS_FORMAT") {
+			t.Fatalf("unexpected format setting %+v", s)
+		}
+	}
+}
@@ -0,0 +1,488 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"syscall"
+
+	"github.com/zalando/go-keyring"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/term"
+)
+
+const (
+	configDirName      = "tartarus"
+	profilesFileName   = "profiles.json"
+	credentialsDirName = "credentials"
+	defaultProfileName = "default"
+	keyringService     = "tartarus"
+
+	credentialStoreAuto          = "auto"
+	credentialStoreKeyring       = "keyring"
+	credentialStoreFile          = "file"
+	credentialStoreEncryptedFile = "encrypted-file"
+
+	// credentialsPassphraseEnv supplies the passphrase for the encrypted-file backend
+	// on machines where nobody is around to type it.
+	credentialsPassphraseEnv = "TARTARUS_CREDENTIALS_PASSPHRASE"
+)
+
+// profile holds the per-account settings that would otherwise have to be passed as flags on every run.
+// Secrets are never stored here; the token lives in the profile's credential store.
+type profile struct {
+	SupabaseURL        string `json:"supabase_url,omitempty"`
+	SupabaseAnonKey    string `json:"supabase_anon_key,omitempty"`
+	HardwareProviderID int    `json:"hardware_provider_id,omitempty"`
+	Email              string `json:"email,omitempty"`
+	CredentialStore    string `json:"credential_store,omitempty"`
+	SecretsRecipient   string `json:"secrets_recipient,omitempty"` // Path to the operator public key secrets are encrypted to
+}
+
+// profileNamePattern restricts profile names to characters that are safe in a file name,
+// since a profile's credentials file is named after it.
+var profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// validateProfileName rejects profile names that could escape the credentials directory.
+func validateProfileName(name string) error {
+	if !profileNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q: use only letters, digits, '-' and '_'", name)
+	}
+	return nil
+}
+
+// configDir returns the tartarus directory under the user's XDG config dir.
+// XDG_CONFIG_HOME is honoured on every platform, not only on Linux.
+func configDir() (string, error) {
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		return filepath.Join(xdg, configDirName), nil
+	}
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate user config directory: %w", err)
+	}
+	return filepath.Join(base, configDirName), nil
+}
+
+// loadProfiles reads every profile from profiles.json. A missing file yields an empty map.
+func loadProfiles() (map[string]profile, error) {
+	dir, err := configDir()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, profilesFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]profile{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profiles: %w", err)
+	}
+
+	profiles := map[string]profile{}
+	if err := json.Unmarshal(data, &profiles); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", profilesFileName, err)
+	}
+	return profiles, nil
+}
+
+// saveProfile creates or replaces a single profile in profiles.json.
+func saveProfile(name string, p profile) error {
+	if err := validateProfileName(name); err != nil {
+		return err
+	}
+	profiles, err := loadProfiles()
+	if err != nil {
+		return err
+	}
+	profiles[name] = p
+
+	dir, err := configDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(profiles, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal profiles: %w", err)
+	}
+	return os.WriteFile(filepath.Join(dir, profilesFileName), data, 0600)
+}
+
+// updateProfile applies fn to the named profile and saves it.
+func updateProfile(name string, fn func(p *profile)) error {
+	profiles, err := loadProfiles()
+	if err != nil {
+		return err
+	}
+	p := profiles[name]
+	fn(&p)
+	return saveProfile(name, p)
+}
+
+// flagWasSet reports whether any of the given flag names was passed explicitly on the command line.
+func flagWasSet(fs *flag.FlagSet, names ...string) bool {
+	if fs == nil {
+		return false
+	}
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		for _, name := range names {
+			if f.Name == name {
+				set = true
+			}
+		}
+	})
+	return set
+}
+
+// applyProfile fills in settings from the selected profile for every flag that was not set explicitly.
+func applyProfile(fs *flag.FlagSet, args *AuthArgs) (profile, error) {
+	if args.Profile == "" {
+		args.Profile = defaultProfileName
+	}
+	if err := validateProfileName(args.Profile); err != nil {
+		return profile{}, err
+	}
+
+	profiles, err := loadProfiles()
+	if err != nil {
+		return profile{}, err
+	}
+	p, ok := profiles[args.Profile]
+	if !ok && args.Profile != defaultProfileName {
+		fmt.Fprintf(os.Stderr, "Profile %q does not exist yet, it will be created after a successful login.\n", args.Profile)
+	}
+
+	if flagWasSet(fs, "password") {
+		fmt.Fprintf(os.Stderr, "Warning: --password is visible to other users in the process list. Prefer --password-file, --password-stdin or %s.\n", passwordEnv)
+	}
+
+	if p.SupabaseURL != "" && !flagWasSet(fs, "supabase-url") {
+		args.SupabaseURL = p.SupabaseURL
+	}
+	if p.SupabaseAnonKey != "" && !flagWasSet(fs, "supabase-anon-key") {
+		args.SupabaseAnonKey = p.SupabaseAnonKey
+	}
+	if p.Email != "" && !flagWasSet(fs, "e", "email") {
+		args.Email = p.Email
+	}
+	if p.CredentialStore != "" && !flagWasSet(fs, "credential-store") {
+		args.CredentialStore = p.CredentialStore
+	}
+	return p, nil
+}
+
+// credentialStore persists the cached token for a profile.
+type credentialStore interface {
+	// Load returns the stored token, or nil if the profile has none.
+	Load(profileName string) (*cachedToken, error)
+	Save(profileName string, token *cachedToken) error
+	Delete(profileName string) error
+	// Location describes where the token is kept, for user-facing messages.
+	Location(profileName string) string
+}
+
+// newCredentialStore returns the backend with the given name.
+// "auto" uses the OS keyring when one is reachable and the config dir otherwise.
+func newCredentialStore(name string) (credentialStore, error) {
+	switch name {
+	case credentialStoreKeyring:
+		return keyringStore{}, nil
+	case credentialStoreFile:
+		return fileStore{}, nil
+	case credentialStoreEncryptedFile:
+		return &encryptedFileStore{}, nil
+	case credentialStoreAuto, "":
+		if keyringAvailable() {
+			return keyringStore{}, nil
+		}
+		return fileStore{}, nil
+	default:
+		return nil, fmt.Errorf("unknown credential store %q (expected auto, keyring, file or encrypted-file)", name)
+	}
+}
+
+// keyringAvailable probes the OS keyring. Headless Linux machines usually have no secret service running.
+func keyringAvailable() bool {
+	_, err := keyring.Get(keyringService, "__probe__")
+	return err == nil || errors.Is(err, keyring.ErrNotFound)
+}
+
+// keyringStore keeps tokens in the OS keyring (macOS Keychain, Windows Credential Manager, Secret Service).
+type keyringStore struct{}
+
+func (keyringStore) Load(profileName string) (*cachedToken, error) {
+	data, err := keyring.Get(keyringService, profileName)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token from OS keyring: %w", err)
+	}
+
+	var token cachedToken
+	if err := json.Unmarshal([]byte(data), &token); err != nil {
+		return nil, fmt.Errorf("failed to parse token from OS keyring: %w", err)
+	}
+	return &token, nil
+}
+
+func (keyringStore) Save(profileName string, token *cachedToken) error {
+	data, err := json.Marshal(token)
+	if err != nil {
+		return fmt.Errorf("failed to marshal token: %w", err)
+	}
+	if err := keyring.Set(keyringService, profileName, string(data)); err != nil {
+		return fmt.Errorf("failed to write token to OS keyring: %w", err)
+	}
+	return nil
+}
+
+func (keyringStore) Delete(profileName string) error {
+	err := keyring.Delete(keyringService, profileName)
+	if err != nil && !errors.Is(err, keyring.ErrNotFound) {
+		return fmt.Errorf("failed to delete token from OS keyring: %w", err)
+	}
+	return nil
+}
+
+func (keyringStore) Location(profileName string) string {
+	return fmt.Sprintf("the OS keyring (service %q, account %q)", keyringService, profileName)
+}
+
+// credentialsPath returns the path of a profile's credentials file with the given extension.
+func credentialsPath(profileName, ext string) (string, error) {
+	if err := validateProfileName(profileName); err != nil {
+		return "", err
+	}
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, credentialsDirName, profileName+ext), nil
+}
+
+// writeCredentialsFile writes data to the credentials dir, readable only by the current user.
+func writeCredentialsFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create credentials directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// removeCredentialsFile deletes a credentials file, ignoring files that don't exist.
+func removeCredentialsFile(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete %s: %w", path, err)
+	}
+	return nil
+}
+
+// fileStore keeps tokens as JSON files with 0600 permissions in the config dir.
+type fileStore struct{}
+
+func (fileStore) Load(profileName string) (*cachedToken, error) {
+	path, err := credentialsPath(profileName, ".json")
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var token cachedToken
+	if err := json.Unmarshal(data, &token); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return &token, nil
+}
+
+func (fileStore) Save(profileName string, token *cachedToken) error {
+	path, err := credentialsPath(profileName, ".json")
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(token, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal token: %w", err)
+	}
+	return writeCredentialsFile(path, data)
+}
+
+func (fileStore) Delete(profileName string) error {
+	path, err := credentialsPath(profileName, ".json")
+	if err != nil {
+		return err
+	}
+	return removeCredentialsFile(path)
+}
+
+func (fileStore) Location(profileName string) string {
+	path, _ := credentialsPath(profileName, ".json")
+	return path
+}
+
+// encryptedFile is the on-disk shape of an encrypted-file credential.
+type encryptedFile struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// scrypt parameters recommended for interactive logins as of 2017.
+const (
+	scryptN      = 1 << 15
+	scryptR      = 8
+	scryptP      = 1
+	scryptSaltSz = 16
+)
+
+// encryptedFileStore keeps tokens in the config dir, sealed with XChaCha20-Poly1305 under a
+// key derived from a passphrase. It is meant for headless servers without an OS keyring.
+type encryptedFileStore struct {
+	passphrase []byte
+}
+
+// getPassphrase reads the passphrase from the environment or prompts for it once per run.
+func (s *encryptedFileStore) getPassphrase() ([]byte, error) {
+	if s.passphrase != nil {
+		return s.passphrase, nil
+	}
+	if v := os.Getenv(credentialsPassphraseEnv); v != "" {
+		s.passphrase = []byte(v)
+		return s.passphrase, nil
+	}
+
+	fmt.Fprint(os.Stderr, "Enter credentials passphrase: ")
+	bytePassphrase, err := term.ReadPassword(int(syscall.Stdin))
+	fmt.Fprintln(os.Stderr) // Newline after passphrase input
+	if err != nil {
+		return nil, fmt.Errorf("failed to read passphrase (set %s for non-interactive use): %w", credentialsPassphraseEnv, err)
+	}
+	if len(bytePassphrase) == 0 {
+		return nil, errors.New("credentials passphrase must not be empty")
+	}
+	s.passphrase = bytePassphrase
+	return s.passphrase, nil
+}
+
+func (s *encryptedFileStore) Load(profileName string) (*cachedToken, error) {
+	path, err := credentialsPath(profileName, ".json.enc")
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var ef encryptedFile
+	if err := json.Unmarshal(data, &ef); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if ef.Version != 1 || ef.KDF != "scrypt" {
+		return nil, fmt.Errorf("unsupported encrypted credentials format in %s", path)
+	}
+
+	passphrase, err := s.getPassphrase()
+	if err != nil {
+		return nil, err
+	}
+	key, err := scrypt.Key(passphrase, ef.Salt, scryptN, scryptR, scryptP, chacha20poly1305.KeySize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, ef.Nonce, ef.Ciphertext, []byte(profileName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt %s: wrong passphrase or corrupted file", path)
+	}
+
+	var token cachedToken
+	if err := json.Unmarshal(plaintext, &token); err != nil {
+		return nil, fmt.Errorf("failed to parse decrypted credentials: %w", err)
+	}
+	return &token, nil
+}
+
+func (s *encryptedFileStore) Save(profileName string, token *cachedToken) error {
+	path, err := credentialsPath(profileName, ".json.enc")
+	if err != nil {
+		return err
+	}
+	plaintext, err := json.Marshal(token)
+	if err != nil {
+		return fmt.Errorf("failed to marshal token: %w", err)
+	}
+
+	passphrase, err := s.getPassphrase()
+	if err != nil {
+		return err
+	}
+	ef := encryptedFile{
+		Version: 1,
+		KDF:     "scrypt",
+		Salt:    make([]byte, scryptSaltSz),
+		Nonce:   make([]byte, chacha20poly1305.NonceSizeX),
+	}
+	if _, err := rand.Read(ef.Salt); err != nil {
+		return err
+	}
+	if _, err := rand.Read(ef.Nonce); err != nil {
+		return err
+	}
+	key, err := scrypt.Key(passphrase, ef.Salt, scryptN, scryptR, scryptP, chacha20poly1305.KeySize)
+	if err != nil {
+		return fmt.Errorf("failed to derive key: %w", err)
+	}
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return err
+	}
+	// Bind the ciphertext to the profile name so files can't be swapped between profiles.
+	ef.Ciphertext = aead.Seal(nil, ef.Nonce, plaintext, []byte(profileName))
+
+	data, err := json.MarshalIndent(ef, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal encrypted credentials: %w", err)
+	}
+	return writeCredentialsFile(path, data)
+}
+
+func (s *encryptedFileStore) Delete(profileName string) error {
+	path, err := credentialsPath(profileName, ".json.enc")
+	if err != nil {
+		return err
+	}
+	return removeCredentialsFile(path)
+}
+
+func (s *encryptedFileStore) Location(profileName string) string {
+	path, _ := credentialsPath(profileName, ".json.enc")
+	return path
+}
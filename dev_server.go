@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/multisig-labs/tartarus/devserver"
+)
+
+// DevServerArgs defines the arguments for the 'dev-server' subcommand.
+type DevServerArgs struct {
+	Addr         string        `cli:"--addr, Address to listen on" default:"127.0.0.1:54321"`
+	Email        string        `cli:"--email, Email of the account to create" default:"dev@example.com"`
+	Password     string        `cli:"--password, Password of the account to create" default:"dev-password"`
+	AnonKey      string        `cli:"--anon-key, Key clients pass as --supabase-anon-key" default:"dev-anon-key"`
+	ServiceKey   string        `cli:"--service-key, Key clients pass as --service-role-key" default:"dev-service-role-key"`
+	TokenTTL     time.Duration `cli:"--token-ttl, Lifetime of issued access tokens" default:"1h"`
+	InjectErrors []string      `cli:"--inject-error, Fail requests: METHOD:PATH:STATUS[:COUNT], e.g. POST:/rest/v1/nodes:503:2 (repeatable)"`
+}
+
+// parseInjectedError parses a METHOD:PATH:STATUS[:COUNT] spec. METHOD may be * for any method.
+func parseInjectedError(spec string) (method, path string, status, count int, err error) {
+	parts := strings.Split(spec, ":")
+	if len(parts) != 3 && len(parts) != 4 {
+		return "", "", 0, 0, fmt.Errorf("invalid --inject-error %q: expected METHOD:PATH:STATUS[:COUNT]", spec)
+	}
+	method, path = strings.ToUpper(parts[0]), parts[1]
+	if method == "*" {
+		method = ""
+	}
+	if status, err = strconv.Atoi(parts[2]); err != nil || status < 400 || status > 599 {
+		return "", "", 0, 0, fmt.Errorf("invalid --inject-error %q: status must be 4xx or 5xx", spec)
+	}
+	count = 1
+	if len(parts) == 4 {
+		if count, err = strconv.Atoi(parts[3]); err != nil || count <= 0 {
+			return "", "", 0, 0, fmt.Errorf("invalid --inject-error %q: count must be positive", spec)
+		}
+	}
+	return method, path, status, count, nil
+}
+
+// runDevServerCommand is the handler for the "dev-server" subcommand. It serves an in-memory
+// stand-in for the Supabase auth and nodes table endpoints until interrupted.
+func runDevServerCommand() {
+	var devArgs DevServerArgs
+	parseArgs("dev-server", &devArgs)
+
+	srv := devserver.New(devserver.Options{AnonKey: devArgs.AnonKey, ServiceKey: devArgs.ServiceKey, TokenTTL: devArgs.TokenTTL})
+	userID := srv.AddUser(devArgs.Email, devArgs.Password)
+	for _, spec := range devArgs.InjectErrors {
+		method, path, status, count, err := parseInjectedError(spec)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		srv.InjectError(method, path, status, count)
+	}
+
+	listener, err := net.Listen("tcp", devArgs.Addr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listening on %s: %v\n", devArgs.Addr, err)
+		os.Exit(1)
+	}
+	url := "http://" + listener.Addr().String()
+	fmt.Fprintf(os.Stderr, "Dev server listening on %s. Data is kept in memory and lost on exit.\n", url)
+	fmt.Fprintf(os.Stderr, "  User:        %s / %s (ID %s)\n", devArgs.Email, devArgs.Password, userID)
+	fmt.Fprintf(os.Stderr, "  Anon key:    %s\n", srv.AnonKey())
+	fmt.Fprintf(os.Stderr, "  Service key: %s\n", srv.ServiceKey())
+	fmt.Fprintf(os.Stderr, "Point the CLI at it with:\n  --supabase-url %s --supabase-anon-key %s --email %s --profile dev\n",
+		url, srv.AnonKey(), devArgs.Email)
+
+	if err := http.Serve(listener, srv); err != nil {
+		fmt.Fprintf(os.Stderr, "Error serving: %v\n", err)
+		os.Exit(1)
+	}
+}
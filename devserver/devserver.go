@@ -0,0 +1,582 @@
+// Package devserver is an in-process stand-in for the parts of Supabase that tartarus talks to:
+// GoTrue password and refresh-token grants, and the PostgREST nodes table.
+// It keeps everything in memory, so the upload flow can be exercised offline in tests and CI.
+package devserver
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Default keys, so local tooling can be pointed at a dev server without copying keys around.
+const (
+	DefaultAnonKey    = "dev-anon-key"
+	DefaultServiceKey = "dev-service-role-key"
+	DefaultTokenTTL   = time.Hour
+)
+
+const (
+	tokenPath  = "/auth/v1/token"
+	userPath   = "/auth/v1/user"
+	logoutPath = "/auth/v1/logout"
+	nodesPath  = "/rest/v1/nodes"
+)
+
+// Options configures a Server. Zero values fall back to the defaults above.
+type Options struct {
+	AnonKey    string
+	ServiceKey string
+	TokenTTL   time.Duration
+}
+
+// Row is a row of the nodes table, keyed by column name.
+type Row map[string]interface{}
+
+type user struct {
+	ID       string
+	Email    string
+	Password string
+}
+
+type token struct {
+	User    *user
+	Expires time.Time
+}
+
+// fault is an injected error response for requests matching Method and Path.
+type fault struct {
+	Method, Path string
+	Status       int
+	Remaining    int
+}
+
+// Server is an http.Handler emulating Supabase auth and the nodes table.
+// Rows are only visible to the user that owns them, as with row-level security;
+// the service key sees and may write every row.
+type Server struct {
+	opts Options
+
+	mu       sync.Mutex
+	users    map[string]*user // by email
+	tokens   map[string]token // access tokens
+	refresh  map[string]*user // refresh tokens, single use
+	rows     []Row
+	faults   []*fault
+	requests map[string]int // "METHOD /path" -> count
+}
+
+// New returns an empty server.
+func New(opts Options) *Server {
+	if opts.AnonKey == "" {
+		opts.AnonKey = DefaultAnonKey
+	}
+	if opts.ServiceKey == "" {
+		opts.ServiceKey = DefaultServiceKey
+	}
+	if opts.TokenTTL <= 0 {
+		opts.TokenTTL = DefaultTokenTTL
+	}
+	return &Server{
+		opts:     opts,
+		users:    map[string]*user{},
+		tokens:   map[string]token{},
+		refresh:  map[string]*user{},
+		requests: map[string]int{},
+	}
+}
+
+// AnonKey returns the key clients must send as apikey.
+func (s *Server) AnonKey() string { return s.opts.AnonKey }
+
+// ServiceKey returns the key that bypasses row-level security.
+func (s *Server) ServiceKey() string { return s.opts.ServiceKey }
+
+// AddUser registers an account that can sign in with the password grant and returns its ID.
+func (s *Server) AddUser(email, password string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if u, ok := s.users[email]; ok {
+		u.Password = password
+		return u.ID
+	}
+	u := &user{ID: fmt.Sprintf("00000000-0000-4000-8000-%012d", len(s.users)+1), Email: email, Password: password}
+	s.users[email] = u
+	return u.ID
+}
+
+// InjectError makes the next count requests matching method and path fail with status.
+// An empty method matches any method. Faults are checked before authentication.
+func (s *Server) InjectError(method, path string, status, count int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &fault{Method: method, Path: path, Status: status, Remaining: count})
+}
+
+// ExpireTokens invalidates every access token issued so far, as if they had all timed out.
+// Refresh tokens stay valid.
+func (s *Server) ExpireTokens() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, t := range s.tokens {
+		t.Expires = time.Now().Add(-time.Second)
+		s.tokens[k] = t
+	}
+}
+
+// Rows returns a copy of every row in the nodes table, in insertion order.
+func (s *Server) Rows() []Row {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rows := make([]Row, len(s.rows))
+	for i, r := range s.rows {
+		rows[i] = copyRow(r)
+	}
+	return rows
+}
+
+// Requests returns how many requests were received for method and path, including failed ones.
+func (s *Server) Requests(method, path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[method+" "+path]
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests[r.Method+" "+r.URL.Path]++
+
+	if status := s.takeFault(r); status != 0 {
+		if status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable {
+			w.Header().Set("Retry-After", "0")
+		}
+		writeJSON(w, status, map[string]string{"message": fmt.Sprintf("injected %d", status)})
+		return
+	}
+
+	apikey := r.Header.Get("apikey")
+	if apikey != s.opts.AnonKey && apikey != s.opts.ServiceKey {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"message": "Invalid API key"})
+		return
+	}
+
+	switch {
+	case r.URL.Path == tokenPath && r.Method == http.MethodPost:
+		s.handleToken(w, r)
+	case r.URL.Path == userPath && r.Method == http.MethodGet:
+		s.handleUser(w, r)
+	case r.URL.Path == logoutPath && r.Method == http.MethodPost:
+		s.handleLogout(w, r)
+	case r.URL.Path == nodesPath:
+		s.handleNodes(w, r)
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]string{"message": "not found"})
+	}
+}
+
+func (s *Server) takeFault(r *http.Request) int {
+	for i, f := range s.faults {
+		if (f.Method == "" || f.Method == r.Method) && f.Path == r.URL.Path {
+			f.Remaining--
+			if f.Remaining <= 0 {
+				s.faults = append(s.faults[:i], s.faults[i+1:]...)
+			}
+			return f.Status
+		}
+	}
+	return 0
+}
+
+// handleToken implements the password and refresh_token grants.
+func (s *Server) handleToken(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Email        string `json:"email"`
+		Password     string `json:"password"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeAuthError(w, http.StatusBadRequest, "invalid_request", "invalid JSON body")
+		return
+	}
+
+	var u *user
+	switch r.URL.Query().Get("grant_type") {
+	case "password":
+		u = s.users[body.Email]
+		if u == nil || u.Password != body.Password {
+			writeAuthError(w, http.StatusBadRequest, "invalid_grant", "Invalid login credentials")
+			return
+		}
+	case "refresh_token":
+		u = s.refresh[body.RefreshToken]
+		if u == nil {
+			writeAuthError(w, http.StatusBadRequest, "invalid_grant", "Invalid Refresh Token: Refresh Token Not Found")
+			return
+		}
+		delete(s.refresh, body.RefreshToken)
+	default:
+		writeAuthError(w, http.StatusBadRequest, "unsupported_grant_type", "unsupported grant_type")
+		return
+	}
+
+	expires := time.Now().Add(s.opts.TokenTTL)
+	access := newJWT(u, expires)
+	refreshToken := randomToken()
+	s.tokens[access] = token{User: u, Expires: expires}
+	s.refresh[refreshToken] = u
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token":  access,
+		"token_type":    "bearer",
+		"expires_in":    int(s.opts.TokenTTL.Seconds()),
+		"expires_at":    expires.Unix(),
+		"refresh_token": refreshToken,
+		"user":          map[string]string{"id": u.ID, "email": u.Email},
+	})
+}
+
+func (s *Server) handleUser(w http.ResponseWriter, r *http.Request) {
+	u, ok := s.authenticate(w, r)
+	if !ok {
+		return
+	}
+	if u == nil {
+		writeJSON(w, http.StatusForbidden, map[string]string{"message": "service key has no user"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"id": u.ID, "email": u.Email})
+}
+
+func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
+	u, ok := s.authenticate(w, r)
+	if !ok {
+		return
+	}
+	for k, t := range s.tokens {
+		if t.User == u {
+			delete(s.tokens, k)
+		}
+	}
+	for k, ru := range s.refresh {
+		if ru == u {
+			delete(s.refresh, k)
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// authenticate resolves the bearer token. It returns a nil user for the service key,
+// and writes a 401 and returns false if the token is missing, unknown or expired.
+func (s *Server) authenticate(w http.ResponseWriter, r *http.Request) (*user, bool) {
+	bearer := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if bearer == s.opts.ServiceKey {
+		return nil, true
+	}
+	t, ok := s.tokens[bearer]
+	switch {
+	case !ok:
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"code": "PGRST301", "message": "JWT invalid"})
+		return nil, false
+	case time.Now().After(t.Expires):
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"code": "PGRST301", "message": "JWT expired"})
+		return nil, false
+	}
+	return t.User, true
+}
+
+// handleNodes implements the subset of PostgREST the CLI uses on the nodes table.
+func (s *Server) handleNodes(w http.ResponseWriter, r *http.Request) {
+	u, ok := s.authenticate(w, r)
+	if !ok {
+		return
+	}
+	query := r.URL.Query()
+	filters, err := parseFilters(query)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"code": "PGRST100", "message": err.Error()})
+		return
+	}
+	visible := func(row Row) bool {
+		return (u == nil || row["user_id"] == u.ID) && filters.match(row)
+	}
+	representation := strings.Contains(r.Header.Get("Prefer"), "return=representation")
+
+	switch r.Method {
+	case http.MethodGet:
+		var rows []Row
+		for _, row := range s.rows {
+			if visible(row) {
+				rows = append(rows, row)
+			}
+		}
+		rows, err = orderAndPage(rows, query)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"code": "PGRST100", "message": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, project(rows, query.Get("select")))
+
+	case http.MethodPost:
+		rows, err := decodeRows(r.Body)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"code": "PGRST102", "message": err.Error()})
+			return
+		}
+		// The whole batch is one transaction: any bad row rejects all of them.
+		seen := map[string]bool{}
+		for _, row := range s.rows {
+			seen[fmt.Sprint(row["node_id"])] = true
+		}
+		for _, row := range rows {
+			id, _ := row["node_id"].(string)
+			if id == "" {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"code": "23502", "message": `null value in column "node_id" violates not-null constraint`})
+				return
+			}
+			if u != nil && row["user_id"] != u.ID {
+				writeJSON(w, http.StatusForbidden, map[string]string{"code": "42501", "message": `new row violates row-level security policy for table "nodes"`})
+				return
+			}
+			if seen[id] {
+				writeJSON(w, http.StatusConflict, map[string]string{
+					"code":    "23505",
+					"message": `duplicate key value violates unique constraint "nodes_pkey"`,
+					"details": fmt.Sprintf("Key (node_id)=(%s) already exists.", id),
+				})
+				return
+			}
+			seen[id] = true
+		}
+		s.rows = append(s.rows, rows...)
+		if !representation {
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+		writeJSON(w, http.StatusCreated, project(rows, query.Get("select")))
+
+	case http.MethodPatch:
+		var update Row
+		if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"code": "PGRST102", "message": err.Error()})
+			return
+		}
+		if _, ok := update["node_id"]; ok {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"code": "PGRST102", "message": "node_id cannot be updated"})
+			return
+		}
+		var updated []Row
+		for _, row := range s.rows {
+			if visible(row) {
+				for k, v := range update {
+					row[k] = v
+				}
+				updated = append(updated, row)
+			}
+		}
+		s.respondChanged(w, updated, representation, query.Get("select"))
+
+	case http.MethodDelete:
+		var deleted []Row
+		kept := s.rows[:0]
+		for _, row := range s.rows {
+			if visible(row) {
+				deleted = append(deleted, row)
+			} else {
+				kept = append(kept, row)
+			}
+		}
+		s.rows = kept
+		s.respondChanged(w, deleted, representation, query.Get("select"))
+
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"message": "method not allowed"})
+	}
+}
+
+func (s *Server) respondChanged(w http.ResponseWriter, rows []Row, representation bool, sel string) {
+	if !representation {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeJSON(w, http.StatusOK, project(rows, sel))
+}
+
+// filters are the column=op.value query parameters of a PostgREST request. Only eq and in are supported.
+type filters map[string]map[string]bool
+
+func parseFilters(query map[string][]string) (filters, error) {
+	f := filters{}
+	for column, values := range query {
+		switch column {
+		case "select", "order", "limit", "offset", "on_conflict", "columns":
+			continue
+		}
+		for _, v := range values {
+			allowed := map[string]bool{}
+			switch {
+			case strings.HasPrefix(v, "eq."):
+				allowed[strings.TrimPrefix(v, "eq.")] = true
+			case strings.HasPrefix(v, "in.(") && strings.HasSuffix(v, ")"):
+				for _, item := range strings.Split(v[len("in.("):len(v)-1], ",") {
+					allowed[strings.Trim(item, `"`)] = true
+				}
+			default:
+				return nil, fmt.Errorf("unsupported filter %s=%s", column, v)
+			}
+			f[column] = allowed
+		}
+	}
+	return f, nil
+}
+
+func (f filters) match(row Row) bool {
+	for column, allowed := range f {
+		if !allowed[cellString(row[column])] {
+			return false
+		}
+	}
+	return true
+}
+
+// orderAndPage applies the order, offset and limit parameters.
+func orderAndPage(rows []Row, query map[string][]string) ([]Row, error) {
+	get := func(k string) string {
+		if v := query[k]; len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	}
+	if order := get("order"); order != "" {
+		column, dir, _ := strings.Cut(order, ".")
+		desc := dir == "desc"
+		sort.SliceStable(rows, func(i, j int) bool {
+			a, b := cellString(rows[i][column]), cellString(rows[j][column])
+			if desc {
+				return a > b
+			}
+			return a < b
+		})
+	}
+	if v := get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid offset %q", v)
+		}
+		if n > len(rows) {
+			n = len(rows)
+		}
+		rows = rows[n:]
+	}
+	if v := get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid limit %q", v)
+		}
+		if n < len(rows) {
+			rows = rows[:n]
+		}
+	}
+	return rows, nil
+}
+
+// project keeps only the selected columns. An empty select or * returns every column.
+func project(rows []Row, sel string) []Row {
+	out := make([]Row, 0, len(rows))
+	for _, row := range rows {
+		if sel == "" || sel == "*" {
+			out = append(out, copyRow(row))
+			continue
+		}
+		p := Row{}
+		for _, column := range strings.Split(sel, ",") {
+			column = strings.TrimSpace(column)
+			p[column] = row[column]
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+// decodeRows reads a single row object or an array of them.
+func decodeRows(body io.Reader) ([]Row, error) {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	data = []byte(strings.TrimSpace(string(data)))
+	if len(data) > 0 && data[0] == '{' {
+		var row Row
+		if err := json.Unmarshal(data, &row); err != nil {
+			return nil, err
+		}
+		return []Row{row}, nil
+	}
+	var rows []Row
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// cellString formats a JSON value the way it appears in a filter.
+func cellString(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func copyRow(r Row) Row {
+	c := make(Row, len(r))
+	for k, v := range r {
+		c[k] = v
+	}
+	return c
+}
+
+// newJWT returns an unsigned JWT carrying the claims the CLI reads. The server only
+// accepts tokens it issued, so there is nothing to verify a signature against.
+func newJWT(u *user, expires time.Time) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`))
+	claims, _ := json.Marshal(map[string]interface{}{
+		"sub":   u.ID,
+		"email": u.Email,
+		"role":  "authenticated",
+		"exp":   expires.Unix(),
+		"jti":   randomToken(),
+	})
+	return header + "." + base64.RawURLEncoding.EncodeToString(claims) + "."
+}
+
+func randomToken() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+func writeAuthError(w http.ResponseWriter, status int, code, description string) {
+	writeJSON(w, status, map[string]string{"error": code, "error_description": description})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
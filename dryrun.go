@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+const (
+	redactedValue      = "[REDACTED]"
+	dryRunUserIDMarker = "<user-id of the authenticated user>"
+)
+
+// uploadPreviewBatch is one request as it would be sent by upload, minus the credentials.
+type uploadPreviewBatch struct {
+	Batch  int                      `json:"batch"`
+	Method string                   `json:"method"`
+	URL    string                   `json:"url"`
+	Prefer string                   `json:"prefer"`
+	Body   []NodeTableInsertPayload `json:"body"`
+}
+
+// dryRunSession returns a session for building payloads offline. The user ID comes from the
+// cached token when there is one; nothing is refreshed or requested over the network.
+func dryRunSession(args *AuthArgs) *authSession {
+	if args.ServiceRoleKey != "" && args.UserID != "" {
+		return &authSession{args: args, UserID: args.UserID}
+	}
+	if store, err := newCredentialStore(args.CredentialStore); err == nil {
+		if s := loadCachedSession(args, store); s != nil {
+			return s
+		}
+	}
+	return &authSession{args: args, UserID: dryRunUserIDMarker}
+}
+
+// redactPayload replaces plaintext secret fields with a marker, keeping it visible that they would be sent.
+// Values sealed to a secrets recipient are ciphertext and are shown as they are.
+func redactPayload(p NodeTableInsertPayload) NodeTableInsertPayload {
+	for _, v := range []*string{&p.StakerCert, &p.StakerKey, &p.BLSPrivateKey} {
+		if *v != "" && !isSealedSecret(*v) {
+			*v = redactedValue
+		}
+	}
+	return p
+}
+
+// previewUpload prints or writes the batches upload would send, with secrets redacted.
+func previewUpload(args *UploadArgs, payloads []NodeTableInsertPayload) error {
+	uploadURL := args.SupabaseURL + nodesTablePath
+
+	batches := []uploadPreviewBatch{}
+	for i := 0; i < len(payloads); i += args.BatchSize {
+		end := i + args.BatchSize
+		if end > len(payloads) {
+			end = len(payloads)
+		}
+		batch := uploadPreviewBatch{
+			Batch:  (i / args.BatchSize) + 1,
+			Method: "POST",
+			URL:    uploadURL,
+			Prefer: insertPreferHeader,
+		}
+		for _, p := range payloads[i:end] {
+			batch.Body = append(batch.Body, redactPayload(p))
+		}
+		batches = append(batches, batch)
+	}
+
+	var preview bytes.Buffer
+	enc := json.NewEncoder(&preview)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(batches); err != nil {
+		return fmt.Errorf("failed to marshal dry-run preview: %w", err)
+	}
+
+	if args.DryRunOutput != "" {
+		if err := os.WriteFile(args.DryRunOutput, preview.Bytes(), 0644); err != nil {
+			return fmt.Errorf("failed to write dry-run preview to %s: %w", args.DryRunOutput, err)
+		}
+		fmt.Fprintf(os.Stderr, "Dry run: %d nodes in %d batches written to %s. Nothing was sent.\n", len(payloads), len(batches), args.DryRunOutput)
+		return nil
+	}
+
+	os.Stdout.Write(preview.Bytes())
+	fmt.Fprintf(os.Stderr, "Dry run: %d nodes in %d batches. Nothing was sent.\n", len(payloads), len(batches))
+	return nil
+}
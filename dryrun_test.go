@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/multisig-labs/tartarus/models"
+)
+
+func TestDryRunRedactsAndSendsNothing(t *testing.T) {
+	isolateConfig(t)
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	nodes := generateNodes(t, 3)
+	data, err := json.Marshal(map[string][]models.Node{"nodes": nodes})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	dataFile := filepath.Join(dir, "nodes.json")
+	if err := os.WriteFile(dataFile, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	args := &UploadArgs{
+		DataFile:           dataFile,
+		HardwareProviderID: 7,
+		Network:            "fuji",
+		BatchSize:          2,
+		IncludeSecrets:     true,
+		DryRun:             true,
+		DryRunOutput:       filepath.Join(dir, "preview.json"),
+		AuthArgs: AuthArgs{
+			SupabaseURL:     srv.URL,
+			Profile:         defaultProfileName,
+			CredentialStore: credentialStoreFile,
+		},
+	}
+	// An expired cached token must not be refreshed during a dry run.
+	expired := &authSession{args: &args.AuthArgs, store: fileStore{}, AccessToken: testJWT(time.Now().Add(-time.Hour)), RefreshToken: "refresh", UserID: "user-1"}
+	expired.save()
+
+	session := dryRunSession(&args.AuthArgs)
+	if session.UserID != "user-1" {
+		t.Fatalf("expected the user ID from the cached token, got %q", session.UserID)
+	}
+	if err := uploadNodesToTable(args, session); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&hits); n != 0 {
+		t.Fatalf("dry run sent %d requests", n)
+	}
+
+	preview, err := os.ReadFile(args.DryRunOutput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var batches []uploadPreviewBatch
+	if err := json.Unmarshal(preview, &batches); err != nil {
+		t.Fatal(err)
+	}
+	if len(batches) != 2 || len(batches[0].Body) != 2 || len(batches[1].Body) != 1 {
+		t.Fatalf("unexpected batches %+v", batches)
+	}
+	for _, b := range batches {
+		for _, p := range b.Body {
+			if p.StakerCert != redactedValue || p.StakerKey != redactedValue || p.BLSPrivateKey != redactedValue {
+				t.Fatalf("%s: secrets were not redacted: %+v", p.NodeID, p)
+			}
+		}
+	}
+	// Check the raw output too, in case a secret shows up in a field other than its own.
+	for _, n := range nodes {
+		for name, secret := range map[string]string{"cert": n.Cert, "key": n.Key, "BLS private key": n.BLSPrivateKey} {
+			for _, line := range strings.Split(secret, "\n") {
+				if len(line) > 32 && !strings.HasPrefix(line, "-----") && strings.Contains(string(preview), line) {
+					t.Fatalf("the %s of %s leaked into the preview", name, n.NodeID)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/jxskiss/mcli"
+	"github.com/multisig-labs/tartarus/models"
+	"github.com/multisig-labs/tartarus/node"
+)
+
+//go:embed templates/*.tmpl
+var builtinTemplates embed.FS
+
+// ExportArgs defines the arguments for the 'export' subcommand.
+type ExportArgs struct {
+	DataFile      string   `cli:"-d, --data-file, Nodes file (JSON, NDJSON or CSV) or a staking directories tree"`
+	Template      string   `cli:"-t, --template, Built-in template name or path to a Go text/template file"`
+	Vars          []string `cli:"--var, Extra value for the template as KEY=VALUE, available as .Vars.KEY (repeatable)"`
+	Output        string   `cli:"-o, --output, Write the rendered output to this file instead of stdout"`
+	ListTemplates bool     `cli:"--list-templates, List the built-in templates and exit"`
+	NodeIDs       []string `cli:"node-ids, NodeIDs to export (defaults to every node in the data file)"`
+}
+
+// exportNode is the public data of a node that templates can use. It never holds secrets.
+type exportNode struct {
+	NodeID          string // NodeID-...
+	NodeIDHex       string // The 20 raw NodeID bytes as 0x-prefixed hex
+	BLSPublicKey    string // 0x-prefixed
+	BLSSignature    string // Proof of possession, 0x-prefixed
+	BLSPubkeyAndSig string // Public key followed by proof of possession, 0x-prefixed
+}
+
+// exportData is the root object templates are executed with.
+type exportData struct {
+	Nodes []exportNode
+	Vars  map[string]string
+}
+
+// templateFuncs are the functions available to export templates.
+var templateFuncs = template.FuncMap{
+	// json encodes a value as JSON, so strings are quoted and escaped correctly.
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// builtinTemplateNames lists the templates shipped with tartarus.
+func builtinTemplateNames() []string {
+	entries, _ := fs.ReadDir(builtinTemplates, "templates")
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, strings.TrimSuffix(e.Name(), ".tmpl"))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// loadExportTemplate parses the template at nameOrPath, or the built-in one with that name.
+// A file takes precedence, so a local template can shadow a built-in one.
+func loadExportTemplate(nameOrPath string) (*template.Template, bool, error) {
+	if data, err := os.ReadFile(nameOrPath); err == nil {
+		t, err := template.New(path.Base(nameOrPath)).Funcs(templateFuncs).Parse(string(data))
+		return t, false, err
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, false, err
+	}
+	data, err := builtinTemplates.ReadFile("templates/" + nameOrPath + ".tmpl")
+	if err != nil {
+		return nil, false, fmt.Errorf("%q is neither a template file nor a built-in template (%s)", nameOrPath, strings.Join(builtinTemplateNames(), ", "))
+	}
+	t, err := template.New(nameOrPath).Funcs(templateFuncs).Parse(string(data))
+	return t, true, err
+}
+
+// exportNodes checks the nodes and keeps only their public data.
+func exportNodes(nodes []models.Node) ([]exportNode, error) {
+	out := make([]exportNode, 0, len(nodes))
+	for _, n := range nodes {
+		if problems := node.Validate(n); len(problems) > 0 {
+			return nil, fmt.Errorf("%s is invalid: %v", n.NodeID, problems[0])
+		}
+		nodeID, err := node.NodeIDBytes(n.NodeID)
+		if err != nil {
+			return nil, err
+		}
+		publicKey, signature := with0x(normalizeHex(n.BLSPublicKey)), with0x(normalizeHex(n.BLSSignature))
+		out = append(out, exportNode{
+			NodeID:          n.NodeID,
+			NodeIDHex:       "0x" + hex.EncodeToString(nodeID),
+			BLSPublicKey:    publicKey,
+			BLSSignature:    signature,
+			BLSPubkeyAndSig: publicKey + normalizeHex(signature),
+		})
+	}
+	return out, nil
+}
+
+// parseTemplateVars parses KEY=VALUE pairs.
+func parseTemplateVars(pairs []string) (map[string]string, error) {
+	vars := make(map[string]string, len(pairs))
+	for _, p := range pairs {
+		key, value, ok := strings.Cut(p, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid --var %q (expected KEY=VALUE)", p)
+		}
+		vars[key] = value
+	}
+	return vars, nil
+}
+
+// renderExport executes the template. Built-in templates produce JSON, which is checked.
+func renderExport(t *template.Template, builtin bool, data exportData) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	if builtin && !json.Valid(buf.Bytes()) {
+		return nil, fmt.Errorf("built-in template %s produced invalid JSON", t.Name())
+	}
+	return buf.Bytes(), nil
+}
+
+// runExportCommand is the handler for the "export" subcommand.
+func runExportCommand() {
+	var exportArgs ExportArgs
+	parseArgs("export", &exportArgs)
+
+	if exportArgs.ListTemplates {
+		for _, name := range builtinTemplateNames() {
+			fmt.Println(name)
+		}
+		return
+	}
+	if exportArgs.DataFile == "" || exportArgs.Template == "" {
+		fmt.Fprintln(os.Stderr, "Error: --data-file and --template are required.")
+		mcli.PrintHelp()
+		os.Exit(1)
+	}
+
+	t, builtin, err := loadExportTemplate(exportArgs.Template)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading template: %v\n", err)
+		os.Exit(1)
+	}
+	vars, err := parseTemplateVars(exportArgs.Vars)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	nodes, err := readSelectedNodes(exportArgs.DataFile, exportArgs.NodeIDs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	public, err := exportNodes(nodes)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	out, err := renderExport(t, builtin, exportData{Nodes: public, Vars: vars})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error rendering template: %v\n", err)
+		os.Exit(1)
+	}
+	if exportArgs.Output == "" {
+		os.Stdout.Write(out)
+		return
+	}
+	if err := os.WriteFile(exportArgs.Output, out, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", exportArgs.Output, err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d nodes to %s\n", len(public), exportArgs.Output)
+}
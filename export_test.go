@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExportBuiltinTemplates(t *testing.T) {
+	nodes := generateNodes(t, 2)
+	public, err := exportNodes(nodes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range builtinTemplateNames() {
+		tmpl, builtin, err := loadExportTemplate(name)
+		if err != nil || !builtin {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if _, err := renderExport(tmpl, builtin, exportData{Nodes: public}); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+
+	tmpl, builtin, _ := loadExportTemplate("getnodeid")
+	out, err := renderExport(tmpl, builtin, exportData{Nodes: public})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var results []struct {
+		NodeID  string `json:"nodeID"`
+		NodePOP blsPOP `json:"nodePOP"`
+	}
+	if err := json.Unmarshal(out, &results); err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[1].NodeID != nodes[1].NodeID || results[1].NodePOP.PublicKey != with0x(nodes[1].BLSPublicKey) || results[1].NodePOP.ProofOfPossession != with0x(nodes[1].BLSSignature) {
+		t.Fatalf("unexpected getnodeid output %s", out)
+	}
+	if strings.Contains(string(out), nodes[0].BLSPrivateKey) {
+		t.Fatal("the BLS private key leaked into the export")
+	}
+}
+
+func TestExportCustomTemplate(t *testing.T) {
+	nodes := generateNodes(t, 1)
+	public, err := exportNodes(nodes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "form.tmpl")
+	if err := os.WriteFile(path, []byte(`{{range .Nodes}}{{.NodeID}} {{$.Vars.fee}}{{end}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	vars, err := parseTemplateVars([]string{"fee=2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl, builtin, err := loadExportTemplate(path)
+	if err != nil || builtin {
+		t.Fatalf("custom template not loaded from file: %v", err)
+	}
+	out, err := renderExport(tmpl, builtin, exportData{Nodes: public, Vars: vars})
+	if err != nil || string(out) != nodes[0].NodeID+" 2" {
+		t.Fatalf("unexpected output %q: %v", out, err)
+	}
+
+	// Templates only see public data, so secret fields do not exist.
+	if err := os.WriteFile(path, []byte(`{{range .Nodes}}{{.Key}}{{end}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tmpl, _, _ = loadExportTemplate(path)
+	if _, err := renderExport(tmpl, false, exportData{Nodes: public}); err == nil {
+		t.Fatal("expected an error for a field that is not exported")
+	}
+	if _, _, err := loadExportTemplate("no-such-template"); err == nil {
+		t.Fatal("expected an error for an unknown template")
+	}
+}
@@ -0,0 +1,360 @@
+package main
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jxskiss/mcli"
+	"github.com/multisig-labs/tartarus/models"
+	"github.com/multisig-labs/tartarus/node"
+)
+
+// Kinds of things inspect reports on.
+const (
+	inspectKindStakingDir = "staking-dir"
+	inspectKindCert       = "cert"
+	inspectKindStakingKey = "staking-key"
+	inspectKindSignerKey  = "signer-key"
+	inspectKindNode       = "node"
+	inspectKindNodeID     = "node-id"
+)
+
+// InspectArgs defines the arguments for the 'inspect' subcommand.
+type InspectArgs struct {
+	JSON  bool     `cli:"--json, Print the reports as JSON"`
+	Paths []string `cli:"paths, Staking directories, PEM files, signer.key files, nodes files (JSON, NDJSON or CSV) or NodeIDs"`
+}
+
+// certReport describes a staking certificate.
+type certReport struct {
+	Subject            string    `json:"subject"`
+	NotBefore          time.Time `json:"not_before"`
+	NotAfter           time.Time `json:"not_after"`
+	KeyAlgorithm       string    `json:"key_algorithm"`
+	KeyBits            int       `json:"key_bits"`
+	SignatureAlgorithm string    `json:"signature_algorithm"`
+}
+
+// inspectReport is what inspect found in one staking dir, file, node record or NodeID.
+type inspectReport struct {
+	Source          string      `json:"source"`
+	Kind            string      `json:"kind"`
+	NodeID          string      `json:"node_id,omitempty"`
+	Cert            *certReport `json:"cert,omitempty"`
+	StakingKey      string      `json:"staking_key_algorithm,omitempty"`
+	BLSPublicKey    string      `json:"bls_public_key,omitempty"`
+	PoPValid        *bool       `json:"pop_valid,omitempty"`
+	HasStakingKey   bool        `json:"has_staking_key"`
+	HasBLSSecretKey bool        `json:"has_bls_secret_key"`
+	ActiveProvider  string      `json:"active_provider,omitempty"`
+	Problems        []string    `json:"problems,omitempty"`
+}
+
+func (r *inspectReport) problem(format string, args ...interface{}) {
+	r.Problems = append(r.Problems, fmt.Sprintf(format, args...))
+}
+
+// describePublicKey returns the algorithm and size of a certificate or private key's public key.
+func describePublicKey(pub crypto.PublicKey) (string, int) {
+	switch k := pub.(type) {
+	case *ecdsa.PublicKey:
+		return "ECDSA " + k.Curve.Params().Name, k.Curve.Params().BitSize
+	case *rsa.PublicKey:
+		return "RSA", k.N.BitLen()
+	case ed25519.PublicKey:
+		return "Ed25519", 256
+	default:
+		return fmt.Sprintf("%T", pub), 0
+	}
+}
+
+// inspectCert fills in the cert details and NodeID of a PEM encoded staking cert.
+func inspectCert(r *inspectReport, certPEM []byte) *x509.Certificate {
+	block, _ := pem.Decode(certPEM)
+	if block == nil || block.Type != "CERTIFICATE" {
+		r.problem("staking cert is not a PEM encoded certificate")
+		return nil
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		r.problem("invalid staking cert: %v", err)
+		return nil
+	}
+	algorithm, bits := describePublicKey(cert.PublicKey)
+	r.Cert = &certReport{
+		Subject:            cert.Subject.String(),
+		NotBefore:          cert.NotBefore,
+		NotAfter:           cert.NotAfter,
+		KeyAlgorithm:       algorithm,
+		KeyBits:            bits,
+		SignatureAlgorithm: cert.SignatureAlgorithm.String(),
+	}
+	// avalanchego is stricter than crypto/x509 about which certs it accepts.
+	nodeID, err := node.NodeIDFromCertPEM(string(certPEM))
+	if err != nil {
+		r.problem("avalanchego rejects the staking cert: %v", err)
+		return cert
+	}
+	if r.NodeID != "" && r.NodeID != nodeID {
+		r.problem("staking cert hashes to %s, not %s", nodeID, r.NodeID)
+	}
+	r.NodeID = nodeID
+	if now := time.Now(); now.Before(cert.NotBefore) || now.After(cert.NotAfter) {
+		r.problem("staking cert is not valid at the current time")
+	}
+	return cert
+}
+
+// inspectStakingKey records the staking key and checks it against cert, if there is one.
+func inspectStakingKey(r *inspectReport, keyPEM []byte, cert *x509.Certificate) {
+	key, err := node.ParseStakingKeyPEM(keyPEM)
+	if err != nil {
+		r.problem("invalid staking key: %v", err)
+		return
+	}
+	r.HasStakingKey = true
+	algorithm, bits := describePublicKey(key.Public())
+	r.StakingKey = fmt.Sprintf("%s (%d bits)", algorithm, bits)
+	if cert == nil {
+		return
+	}
+	if pub, ok := key.Public().(interface{ Equal(crypto.PublicKey) bool }); !ok || !pub.Equal(cert.PublicKey) {
+		r.problem("staking key does not belong to the staking cert")
+	}
+}
+
+// inspectBLS records the BLS public key and checks its proof of possession.
+func inspectBLS(r *inspectReport, publicKeyHex, signatureHex string) {
+	if publicKeyHex == "" {
+		return
+	}
+	r.BLSPublicKey = with0x(normalizeHex(publicKeyHex))
+	valid := node.VerifyProofOfPossession(publicKeyHex, signatureHex) == nil
+	r.PoPValid = &valid
+	if !valid {
+		r.problem("BLS proof of possession is missing or invalid")
+	}
+}
+
+// inspectStakingDir reports on one directory holding staker.crt, staker.key and signer.key.
+func inspectStakingDir(dir string) inspectReport {
+	r := inspectReport{Source: dir, Kind: inspectKindStakingDir}
+	var cert *x509.Certificate
+	if data, err := os.ReadFile(filepath.Join(dir, node.StakerCertFile)); err != nil {
+		r.problem("%s: %v", node.StakerCertFile, err)
+	} else {
+		cert = inspectCert(&r, data)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, node.StakerKeyFile)); err == nil {
+		inspectStakingKey(&r, data, cert)
+	}
+	if _, err := os.Stat(filepath.Join(dir, node.SignerKeyFile)); err == nil {
+		inspectSignerKey(&r, filepath.Join(dir, node.SignerKeyFile))
+	}
+	return r
+}
+
+// inspectSignerKey reports on a raw signer.key.
+func inspectSignerKey(r *inspectReport, path string) {
+	key, err := node.ReadSignerKey(path)
+	if err != nil {
+		r.problem("%v", err)
+		return
+	}
+	r.HasBLSSecretKey = true
+	inspectBLS(r, hex.EncodeToString(key.PublicKey), hex.EncodeToString(key.ProofOfPossession))
+}
+
+// inspectNodeRecord reports on one node from a nodes file.
+func inspectNodeRecord(source string, n models.Node) inspectReport {
+	r := inspectReport{Source: source, Kind: inspectKindNode, NodeID: n.NodeID, ActiveProvider: n.ActiveProvider}
+	if _, err := node.ParseNodeID(n.NodeID); err != nil {
+		r.problem("invalid NodeID %q: %v", n.NodeID, err)
+	}
+	var cert *x509.Certificate
+	if n.Cert != "" {
+		cert = inspectCert(&r, []byte(n.Cert))
+	}
+	if n.Key != "" {
+		inspectStakingKey(&r, []byte(n.Key), cert)
+	}
+	if n.BLSPublicKey == "" {
+		r.problem("BLS public key is missing")
+	}
+	inspectBLS(&r, n.BLSPublicKey, n.BLSSignature)
+	if n.BLSPrivateKey != "" {
+		r.HasBLSSecretKey = true
+		// Validate checks that the BLS private key matches the public key. The other
+		// fields were already reported above.
+		for _, p := range node.Validate(n) {
+			if errors.Is(p, node.ErrBLSPrivateKey) {
+				r.problem("%v", p)
+			}
+		}
+	}
+	return r
+}
+
+// inspectPath reports on everything found at path, which may also be a bare NodeID.
+func inspectPath(path string) ([]inspectReport, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		if strings.HasPrefix(path, "NodeID-") {
+			r := inspectReport{Source: path, Kind: inspectKindNodeID, NodeID: path}
+			if _, err := node.ParseNodeID(path); err != nil {
+				r.problem("invalid NodeID: %v", err)
+			}
+			return []inspectReport{r}, nil
+		}
+		return nil, err
+	}
+
+	if info.IsDir() {
+		dirs, err := node.StakingDirs(path)
+		if err != nil {
+			return nil, err
+		}
+		if len(dirs) == 0 {
+			return nil, fmt.Errorf("%s contains no staking directories", path)
+		}
+		reports := make([]inspectReport, 0, len(dirs))
+		for _, dir := range dirs {
+			reports = append(reports, inspectStakingDir(dir))
+		}
+		return reports, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if block, _ := pem.Decode(data); block != nil {
+		if block.Type == "CERTIFICATE" {
+			r := inspectReport{Source: path, Kind: inspectKindCert}
+			inspectCert(&r, data)
+			return []inspectReport{r}, nil
+		}
+		r := inspectReport{Source: path, Kind: inspectKindStakingKey}
+		inspectStakingKey(&r, data, nil)
+		return []inspectReport{r}, nil
+	}
+	if len(data) == node.BLSSecretKeyLen {
+		r := inspectReport{Source: path, Kind: inspectKindSignerKey}
+		inspectSignerKey(&r, path)
+		return []inspectReport{r}, nil
+	}
+
+	nodes, err := readNodesFile(path)
+	if err != nil {
+		return nil, err
+	}
+	reports := make([]inspectReport, 0, len(nodes))
+	for i, n := range nodes {
+		reports = append(reports, inspectNodeRecord(fmt.Sprintf("%s[%d]", path, i), n))
+	}
+	return reports, nil
+}
+
+// writeInspectReport prints a report as aligned key/value lines.
+func writeInspectReport(w io.Writer, r inspectReport) {
+	yesNo := map[bool]string{true: "present", false: "missing"}
+	line := func(key, value string) { fmt.Fprintf(w, "  %-21s %s\n", key+":", value) }
+
+	fmt.Fprintf(w, "%s (%s)\n", r.Source, r.Kind)
+	if r.NodeID != "" {
+		line("NodeID", r.NodeID)
+	}
+	if c := r.Cert; c != nil {
+		subject := c.Subject
+		if subject == "" {
+			subject = "(empty)"
+		}
+		line("Cert subject", subject)
+		line("Valid", c.NotBefore.UTC().Format(time.RFC3339)+" to "+c.NotAfter.UTC().Format(time.RFC3339))
+		line("Cert key", fmt.Sprintf("%s (%d bits), signed with %s", c.KeyAlgorithm, c.KeyBits, c.SignatureAlgorithm))
+	}
+	if r.Kind != inspectKindNodeID && r.Kind != inspectKindSignerKey && r.Kind != inspectKindCert {
+		value := yesNo[r.HasStakingKey]
+		if r.StakingKey != "" {
+			value += ", " + r.StakingKey
+		}
+		line("Staking key", value)
+	}
+	if r.BLSPublicKey != "" {
+		line("BLS public key", r.BLSPublicKey)
+	}
+	if r.PoPValid != nil {
+		line("Proof of possession", map[bool]string{true: "valid", false: "INVALID"}[*r.PoPValid])
+	}
+	if r.Kind != inspectKindNodeID && r.Kind != inspectKindCert && r.Kind != inspectKindStakingKey {
+		line("BLS secret key", yesNo[r.HasBLSSecretKey])
+	}
+	if r.ActiveProvider != "" {
+		line("Active provider", r.ActiveProvider)
+	}
+	for _, p := range r.Problems {
+		line("Problem", p)
+	}
+}
+
+// runInspectCommand is the handler for the "inspect" subcommand.
+// It exits non-zero if anything could not be read or has problems, so it can gate scripts.
+func runInspectCommand() {
+	var inspectArgs InspectArgs
+	parseArgs("inspect", &inspectArgs)
+	if len(inspectArgs.Paths) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: pass at least one path or NodeID to inspect.")
+		mcli.PrintHelp()
+		os.Exit(1)
+	}
+
+	var reports []inspectReport
+	failed := false
+	for _, path := range inspectArgs.Paths {
+		found, err := inspectPath(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error inspecting %s: %v\n", path, err)
+			failed = true
+			continue
+		}
+		reports = append(reports, found...)
+	}
+	for _, r := range reports {
+		failed = failed || len(r.Problems) > 0
+	}
+
+	if inspectArgs.JSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(reports); err != nil {
+			fmt.Fprintf(os.Stderr, "Error encoding JSON: %v\n", err)
+			os.Exit(1)
+		}
+	} else {
+		var buf bytes.Buffer
+		for i, r := range reports {
+			if i > 0 {
+				buf.WriteString("\n")
+			}
+			writeInspectReport(&buf, r)
+		}
+		os.Stdout.Write(buf.Bytes())
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/multisig-labs/tartarus/node"
+)
+
+func TestInspectPath(t *testing.T) {
+	nodes := generateNodes(t, 2)
+	root := t.TempDir()
+	dir := filepath.Join(root, "a")
+	writeStakingDir(t, dir, nodes[0])
+
+	reports, err := inspectPath(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := reports[0]
+	if len(reports) != 1 || r.Kind != inspectKindStakingDir || r.NodeID != nodes[0].NodeID {
+		t.Fatalf("unexpected reports %+v", reports)
+	}
+	if r.Cert == nil || r.Cert.KeyAlgorithm != "ECDSA P-256" || r.Cert.KeyBits != 256 {
+		t.Fatalf("unexpected cert report %+v", r.Cert)
+	}
+	if !r.HasStakingKey || !r.HasBLSSecretKey || r.PoPValid == nil || !*r.PoPValid || len(r.Problems) > 0 {
+		t.Fatalf("unexpected report %+v", r)
+	}
+	if r.BLSPublicKey != with0x(nodes[0].BLSPublicKey) {
+		t.Fatalf("BLS public key %s, expected %s", r.BLSPublicKey, nodes[0].BLSPublicKey)
+	}
+
+	signer, err := inspectPath(filepath.Join(dir, node.SignerKeyFile))
+	if err != nil || signer[0].Kind != inspectKindSignerKey || signer[0].BLSPublicKey != r.BLSPublicKey {
+		t.Fatalf("unexpected signer.key report %+v: %v", signer, err)
+	}
+
+	// A staking key from another node must be flagged.
+	if err := os.WriteFile(filepath.Join(dir, node.StakerKeyFile), []byte(nodes[1].Key), 0600); err != nil {
+		t.Fatal(err)
+	}
+	reports, err = inspectPath(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if problems := strings.Join(reports[0].Problems, "; "); !strings.Contains(problems, "does not belong") {
+		t.Fatalf("mismatched staking key not reported: %q", problems)
+	}
+
+	// Records in a nodes file are reported one by one, and public-only records have no secrets.
+	public := nodes[1]
+	public.Key, public.BLSPrivateKey = "", ""
+	path := filepath.Join(root, "nodes.json")
+	var buf bytes.Buffer
+	if err := node.WriteNodes(&buf, node.FormatJSON, append(nodes[:1:1], public)); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	reports, err = inspectPath(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 2 || reports[1].NodeID != nodes[1].NodeID || reports[1].HasStakingKey || reports[1].HasBLSSecretKey || len(reports[1].Problems) > 0 {
+		t.Fatalf("unexpected nodes file reports %+v", reports)
+	}
+
+	reports, err = inspectPath("NodeID-notanodeid")
+	if err != nil || reports[0].Kind != inspectKindNodeID || len(reports[0].Problems) == 0 {
+		t.Fatalf("invalid NodeID not reported: %+v, %v", reports, err)
+	}
+}
+
+func TestInspectNodeRecordBLSPrivateKey(t *testing.T) {
+	nodes := generateNodes(t, 2)
+	if r := inspectNodeRecord("nodes.json", nodes[0]); len(r.Problems) > 0 {
+		t.Fatalf("unexpected problems %v", r.Problems)
+	}
+
+	// A BLS private key from another node is the only problem reported.
+	bad := nodes[0]
+	bad.BLSPrivateKey = nodes[1].BLSPrivateKey
+	r := inspectNodeRecord("nodes.json", bad)
+	if len(r.Problems) != 1 || !strings.Contains(r.Problems[0], "does not match the BLS public key") {
+		t.Fatalf("unexpected problems %v", r.Problems)
+	}
+}
@@ -0,0 +1,73 @@
+package node
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ava-labs/avalanchego/utils/crypto/bls"
+	blst "github.com/supranational/blst/bindings/go"
+)
+
+// BLSSecretKeyLen is the size of the raw signer.key avalanchego reads.
+const BLSSecretKeyLen = 32
+
+// BLSKey is the BLS half of a node: the secret key as stored in signer.key, its compressed
+// public key and the proof of possession registered with the P-Chain.
+type BLSKey struct {
+	Secret            []byte
+	PublicKey         []byte
+	ProofOfPossession []byte
+}
+
+// NewBLSKey mirrors bls.NewSecretKey, reading the key material from r, and signs the proof of possession.
+func NewBLSKey(r io.Reader) (BLSKey, error) {
+	var ikm [32]byte
+	if _, err := io.ReadFull(r, ikm[:]); err != nil {
+		return BLSKey{}, fmt.Errorf("couldn't read BLS key material: %w", err)
+	}
+	sk := blst.KeyGen(ikm[:])
+	ikm = [32]byte{} // zero out the ikm
+	return blsKeyFromSecretKey(sk), nil
+}
+
+// BLSKeyFromSecret derives the public key and proof of possession from a raw secret key.
+func BLSKeyFromSecret(secret []byte) (BLSKey, error) {
+	sk, err := bls.SecretKeyFromBytes(secret)
+	if err != nil {
+		return BLSKey{}, err
+	}
+	return blsKeyFromSecretKey(sk), nil
+}
+
+func blsKeyFromSecretKey(sk *bls.SecretKey) BLSKey {
+	// sign the public key
+	publicBytes := bls.PublicKeyToCompressedBytes(bls.PublicFromSecretKey(sk))
+	return BLSKey{
+		Secret:            bls.SecretKeyToBytes(sk),
+		PublicKey:         publicBytes,
+		ProofOfPossession: bls.SignatureToBytes(bls.SignProofOfPossession(sk, publicBytes)),
+	}
+}
+
+// ReadSignerKey loads a raw signer.key, as written by convert and avalanchego.
+func ReadSignerKey(path string) (BLSKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return BLSKey{}, err
+	}
+	if len(data) != BLSSecretKeyLen {
+		trimmed := strings.TrimPrefix(strings.TrimSpace(string(data)), "0x")
+		if _, err := hex.DecodeString(trimmed); err == nil && len(trimmed) == 2*BLSSecretKeyLen {
+			return BLSKey{}, fmt.Errorf("%s is hex encoded; signer.key must hold the raw %d bytes", path, BLSSecretKeyLen)
+		}
+		return BLSKey{}, fmt.Errorf("%s is %d bytes; signer.key must hold the raw %d bytes", path, len(data), BLSSecretKeyLen)
+	}
+	key, err := BLSKeyFromSecret(data)
+	if err != nil {
+		return BLSKey{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return key, nil
+}
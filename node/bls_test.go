@@ -0,0 +1,46 @@
+package node
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBLSKeyRoundTrip(t *testing.T) {
+	key, err := NewBLSKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(key.Secret) != BLSSecretKeyLen {
+		t.Fatalf("secret is %d bytes", len(key.Secret))
+	}
+	if err := VerifyProofOfPossession(hex.EncodeToString(key.PublicKey), hex.EncodeToString(key.ProofOfPossession)); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, SignerKeyFile)
+	if err := os.WriteFile(path, key.Secret, 0600); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadSignerKey(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(read.PublicKey, key.PublicKey) || !bytes.Equal(read.ProofOfPossession, key.ProofOfPossession) {
+		t.Fatal("key read back differs")
+	}
+
+	// A hex encoded key is a common mistake and gets a specific error.
+	hexPath := filepath.Join(dir, "hex.key")
+	if err := os.WriteFile(hexPath, []byte(hex.EncodeToString(key.Secret)+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadSignerKey(hexPath); err == nil || !strings.Contains(err.Error(), "hex encoded") {
+		t.Fatalf("expected a hex encoding error, got %v", err)
+	}
+}
@@ -0,0 +1,156 @@
+package node
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"sync"
+)
+
+// mixedReader hashes every block read from the OS RNG together with a secret seed.
+type mixedReader struct {
+	mu      sync.Mutex
+	base    io.Reader
+	seed    [sha256.Size]byte
+	counter uint64
+	buf     []byte
+}
+
+// MixEntropy returns a reader whose output depends on both base (normally crypto/rand.Reader)
+// and extra, such as dice rolls or bytes from a hardware RNG. Each 32-byte block is
+// HMAC-SHA256(SHA-256(extra), block from base || counter), so the output stays unpredictable
+// as long as either source is. The reader is safe for concurrent use.
+func MixEntropy(base io.Reader, extra []byte) io.Reader {
+	h := sha256.New()
+	h.Write([]byte("tartarus extra entropy v1"))
+	h.Write(extra)
+	m := &mixedReader{base: base}
+	h.Sum(m.seed[:0])
+	return m
+}
+
+func (m *mixedReader) Read(p []byte) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n := 0
+	for n < len(p) {
+		if len(m.buf) == 0 {
+			var block [sha256.Size + 8]byte
+			if _, err := io.ReadFull(m.base, block[:sha256.Size]); err != nil {
+				return n, err
+			}
+			m.counter++
+			binary.BigEndian.PutUint64(block[sha256.Size:], m.counter)
+			mac := hmac.New(sha256.New, m.seed[:])
+			mac.Write(block[:])
+			m.buf = mac.Sum(nil)
+		}
+		c := copy(p[n:], m.buf)
+		m.buf = m.buf[c:]
+		n += c
+	}
+	return n, nil
+}
+
+// deriveECDSAKey derives a P-256 key from r, reading 8 bytes more than the key size so the
+// reduction modulo the group order is unbiased (FIPS 186-4, B.4.1). Unlike ecdsa.GenerateKey,
+// the result depends only on the bytes read, so a fixed reader gives a fixed key.
+func deriveECDSAKey(r io.Reader) (*ecdsa.PrivateKey, error) {
+	curve := elliptic.P256()
+	params := curve.Params()
+	b := make([]byte, params.BitSize/8+8)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+
+	one := big.NewInt(1)
+	d := new(big.Int).SetBytes(b)
+	d.Mod(d, new(big.Int).Sub(params.N, one))
+	d.Add(d, one)
+
+	key := &ecdsa.PrivateKey{D: d}
+	key.PublicKey.Curve = curve
+	key.PublicKey.X, key.PublicKey.Y = curve.ScalarBaseMult(d.FillBytes(make([]byte, params.BitSize/8)))
+	return key, nil
+}
+
+// deterministicSigner signs with nonces derived from the key and digest (RFC 6979),
+// ignoring the rand argument, so the self-signed staking cert is reproducible.
+type deterministicSigner struct {
+	key *ecdsa.PrivateKey
+}
+
+func (s deterministicSigner) Public() crypto.PublicKey {
+	return &s.key.PublicKey
+}
+
+func (s deterministicSigner) Sign(_ io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	if opts.HashFunc() != crypto.SHA256 {
+		return nil, fmt.Errorf("unsupported hash %v", opts.HashFunc())
+	}
+	r, sig, err := signRFC6979(s.key, digest)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(struct{ R, S *big.Int }{r, sig})
+}
+
+// signRFC6979 signs a SHA-256 digest with the nonce generation of RFC 6979, section 3.2.
+// It is only used for the one-off self-signature of a new staking cert.
+func signRFC6979(key *ecdsa.PrivateKey, digest []byte) (*big.Int, *big.Int, error) {
+	params := key.Curve.Params()
+	q := params.N
+	qlen := (q.BitLen() + 7) / 8
+
+	// With SHA-256 on P-256, bits2int is a plain conversion.
+	e := new(big.Int).SetBytes(digest)
+	x := key.D.FillBytes(make([]byte, qlen))
+	h1 := new(big.Int).Mod(e, q).FillBytes(make([]byte, qlen))
+
+	hmacSum := func(k []byte, parts ...[]byte) []byte {
+		mac := hmac.New(sha256.New, k)
+		for _, p := range parts {
+			mac.Write(p)
+		}
+		return mac.Sum(nil)
+	}
+	v := make([]byte, sha256.Size)
+	for i := range v {
+		v[i] = 0x01
+	}
+	k := make([]byte, sha256.Size)
+	k = hmacSum(k, v, []byte{0x00}, x, h1)
+	v = hmacSum(k, v)
+	k = hmacSum(k, v, []byte{0x01}, x, h1)
+	v = hmacSum(k, v)
+
+	for i := 0; i < 1000; i++ {
+		v = hmacSum(k, v)
+		nonce := new(big.Int).SetBytes(v)
+		if nonce.Sign() > 0 && nonce.Cmp(q) < 0 {
+			rx, _ := key.Curve.ScalarBaseMult(nonce.FillBytes(make([]byte, qlen)))
+			r := new(big.Int).Mod(rx, q)
+			if r.Sign() != 0 {
+				s := new(big.Int).Mul(r, key.D)
+				s.Add(s, e)
+				s.Mul(s, new(big.Int).ModInverse(nonce, q))
+				s.Mod(s, q)
+				if s.Sign() != 0 {
+					return r, s, nil
+				}
+			}
+		}
+		k = hmacSum(k, v, []byte{0x00})
+		v = hmacSum(k, v)
+	}
+	return nil, nil, errors.New("failed to find a signature nonce")
+}
@@ -0,0 +1,145 @@
+package node
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"testing"
+	"time"
+)
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+// seededReader returns a deterministic stream for the given seed.
+func seededReader(seed string) io.Reader {
+	return MixEntropy(zeroReader{}, []byte(seed))
+}
+
+func TestSignRFC6979Vector(t *testing.T) {
+	// RFC 6979, appendix A.2.5: P-256 with SHA-256, message "sample".
+	hexInt := func(s string) *big.Int {
+		v, _ := new(big.Int).SetString(s, 16)
+		return v
+	}
+	key := &ecdsa.PrivateKey{D: hexInt("C9AFA9D845BA75166B5C215767B1D6934E50C3DB36E89B127B8A622B120F6721")}
+	key.Curve = elliptic.P256()
+	key.X, key.Y = key.Curve.ScalarBaseMult(key.D.Bytes())
+
+	digest := sha256.Sum256([]byte("sample"))
+	r, s, err := signRFC6979(key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Cmp(hexInt("EFD48B2AACB6A8FD1140DD9CD45E81D69D2C877B56AAF991C34D0EA84EAF3716")) != 0 {
+		t.Errorf("unexpected r %X", r)
+	}
+	if s.Cmp(hexInt("F7CB1C942D657C41D436C7A1B6E29F65F3E900DBB9AFF4064DC4AB2F843ACDA8")) != 0 {
+		t.Errorf("unexpected s %X", s)
+	}
+	if !ecdsa.Verify(&key.PublicKey, digest[:], r, s) {
+		t.Error("signature does not verify")
+	}
+}
+
+func TestGenerateFromIsReproducible(t *testing.T) {
+	issued := time.Date(2025, time.June, 1, 0, 0, 0, 0, time.UTC)
+	a, err := generateAt(seededReader("seed-1"), issued)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := generateAt(seededReader("seed-1"), issued)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a != b {
+		t.Fatalf("same seed gave different nodes: %s and %s", a.NodeID, b.NodeID)
+	}
+	if problems := Validate(a); len(problems) > 0 {
+		t.Fatalf("generated node is invalid: %v", problems)
+	}
+
+	c, err := generateAt(seededReader("seed-2"), issued)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.NodeID == a.NodeID || c.BLSPrivateKey == a.BLSPrivateKey {
+		t.Fatal("different seeds gave the same keys")
+	}
+
+	// Any reader other than crypto/rand.Reader is reproducible, not just MixEntropy's.
+	plain := func() io.Reader { return bytes.NewReader(bytes.Repeat([]byte("plain"), 1000)) }
+	d, err := generateAt(plain(), issued)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, err := generateAt(plain(), issued); err != nil || d != e {
+		t.Fatalf("same reader bytes gave different nodes: %s and %s (%v)", d.NodeID, e.NodeID, err)
+	}
+
+	// A single-worker Generator draws from its reader in the same order. It issues the cert
+	// at the current time, so only the keys are compared.
+	results, err := NewGenerator(WithWorkers(1), WithRand(seededReader("seed-1"))).Generate(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := <-results; n.Key != a.Key || n.BLSPrivateKey != a.BLSPrivateKey {
+		t.Fatalf("generator gave %s, expected the keys of %s", n.NodeID, a.NodeID)
+	}
+}
+
+func TestCertValidity(t *testing.T) {
+	issued := time.Date(2025, time.June, 1, 0, 0, 0, 0, time.UTC)
+	for name, r := range map[string]io.Reader{"crypto/rand": rand.Reader, "mixed": seededReader("seed-1")} {
+		n, err := generateAt(r, issued)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block, _ := pem.Decode([]byte(n.Cert))
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Like staking.NewCertAndKeyBytes, certs are valid for 100 years.
+		if want := issued.AddDate(100, 0, 0); !cert.NotAfter.Equal(want) {
+			t.Errorf("%s: cert expires %v, expected %v", name, cert.NotAfter, want)
+		}
+	}
+}
+
+func TestMixEntropy(t *testing.T) {
+	read := func(r io.Reader) []byte {
+		b := make([]byte, 100)
+		if _, err := io.ReadFull(r, b); err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	base := bytes.Repeat([]byte{7}, 1000)
+
+	same := read(MixEntropy(bytes.NewReader(base), []byte("dice")))
+	if !bytes.Equal(same, read(MixEntropy(bytes.NewReader(base), []byte("dice")))) {
+		t.Fatal("output is not a function of its inputs")
+	}
+	if bytes.Equal(same, read(MixEntropy(bytes.NewReader(base), []byte("other dice")))) {
+		t.Fatal("extra entropy is ignored")
+	}
+	if bytes.Equal(same[:32], same[32:64]) {
+		t.Fatal("repeated base blocks give repeated output")
+	}
+
+	if _, err := MixEntropy(bytes.NewReader(base[:10]), nil).Read(make([]byte, 32)); err == nil {
+		t.Fatal("expected an error when the base reader runs out")
+	}
+}
@@ -1,59 +1,128 @@
 package node
 
 import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
 	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"time"
 
 	"github.com/ava-labs/avalanchego/ids"
 	"github.com/ava-labs/avalanchego/staking"
-	"github.com/ava-labs/avalanchego/utils/crypto/bls"
 
 	"github.com/multisig-labs/tartarus/models"
 )
 
+// Generate creates a node with a fresh staking cert and BLS key, using the OS RNG.
 func Generate() (models.Node, error) {
-	certBytes, keyBytes, err := staking.NewCertAndKeyBytes()
+	return GenerateFrom(rand.Reader)
+}
+
+// GenerateFrom creates a node with all key material drawn from r. Unless r is crypto/rand.Reader,
+// the same bytes always give the same keys, so a seeded reader makes the keys reproducible.
+// The NodeID also depends on the cert's validity period, which starts now.
+func GenerateFrom(r io.Reader) (models.Node, error) {
+	return generateAt(r, time.Now())
+}
+
+// generateAt is GenerateFrom for a staking cert issued at now.
+func generateAt(r io.Reader, now time.Time) (models.Node, error) {
+	n, err := newIdentity(r, now)
 	if err != nil {
 		return models.Node{}, err
 	}
+	if err := addBLSKey(r, &n); err != nil {
+		return models.Node{}, err
+	}
+	return n, nil
+}
 
-	tlsCert, err := staking.LoadTLSCertFromBytes(keyBytes, certBytes)
+// newIdentity creates a staking cert and key and derives the NodeID from them.
+func newIdentity(r io.Reader, now time.Time) (models.Node, error) {
+	certDER, keyPEM, err := newCertAndKey(r, now)
 	if err != nil {
 		return models.Node{}, err
 	}
-
-	stakingCert, err := staking.ParseCertificate(tlsCert.Leaf.Raw)
+	stakingCert, err := staking.ParseCertificate(certDER)
 	if err != nil {
 		return models.Node{}, err
 	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER})
 
-	nodeID := ids.NodeIDFromCert(stakingCert)
+	return models.Node{
+		NodeID: ids.NodeIDFromCert(stakingCert).String(),
+		Cert:   string(certPEM),
+		Key:    string(keyPEM),
+	}, nil
+}
 
-	blsSecret, err := bls.NewSecretKey()
+// newCertAndKey mirrors staking.NewCertAndKeyBytes, drawing all of its randomness from r.
+// Like upstream, the cert is valid for 100 years from now. crypto/rand.Reader goes through
+// crypto/ecdsa as upstream does. Any other reader, which crypto/ecdsa would not draw from
+// reproducibly, gets a key derived from its bytes and an RFC 6979 signature, so the same
+// bytes give the same key. It returns the DER encoded cert and the PEM encoded PKCS #8 key.
+func newCertAndKey(r io.Reader, now time.Time) ([]byte, []byte, error) {
+	var key *ecdsa.PrivateKey
+	var signer crypto.Signer
+	var err error
+	if r == rand.Reader {
+		key, err = ecdsa.GenerateKey(elliptic.P256(), r)
+		signer = key
+	} else {
+		key, err = deriveECDSAKey(r)
+		signer = deterministicSigner{key}
+	}
 	if err != nil {
-		return models.Node{}, err
+		return nil, nil, fmt.Errorf("couldn't generate ecdsa key: %w", err)
 	}
 
-	// sign the nodeID
-	blsPublic := bls.PublicFromSecretKey(blsSecret)
-	blsPublicBytes := bls.PublicKeyToCompressedBytes(blsPublic)
-	blsPrivateBytes := bls.SecretKeyToBytes(blsSecret)
-
-	signature := bls.SignProofOfPossession(blsSecret, blsPublicBytes)
-	sigBytes := bls.SignatureToBytes(signature)
+	certTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(0),
+		NotBefore:             time.Date(2000, time.January, 0, 0, 0, 0, 0, time.UTC),
+		NotAfter:              now.AddDate(100, 0, 0),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+	}
+	certDER, err := x509.CreateCertificate(r, certTemplate, certTemplate, key.Public(), signer)
+	if err != nil {
+		return nil, nil, fmt.Errorf("couldn't create certificate: %w", err)
+	}
 
-	blsPublicHex := hex.EncodeToString(blsPublicBytes)
-	blsPrivateHex := hex.EncodeToString(blsPrivateBytes)
-	sigHex := hex.EncodeToString(sigBytes)
+	privBytes, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("couldn't marshal private key: %w", err)
+	}
+	var keyBuff bytes.Buffer
+	if err := pem.Encode(&keyBuff, &pem.Block{Type: "PRIVATE KEY", Bytes: privBytes}); err != nil {
+		return nil, nil, fmt.Errorf("couldn't write private key: %w", err)
+	}
+	return certDER, keyBuff.Bytes(), nil
+}
 
-	certString := string(certBytes)
-	keyString := string(keyBytes)
+// addBLSKey generates a BLS key for n and signs its proof of possession.
+func addBLSKey(r io.Reader, n *models.Node) error {
+	key, err := NewBLSKey(r)
+	if err != nil {
+		return err
+	}
+	n.BLSPrivateKey = hex.EncodeToString(key.Secret)
+	n.BLSPublicKey = hex.EncodeToString(key.PublicKey)
+	n.BLSSignature = hex.EncodeToString(key.ProofOfPossession)
+	return nil
+}
 
-	return models.Node{
-		NodeID:        nodeID.String(),
-		Cert:          certString,
-		Key:           keyString,
-		BLSPrivateKey: blsPrivateHex,
-		BLSPublicKey:  blsPublicHex,
-		BLSSignature:  sigHex,
-	}, nil
+// RotateBLS returns n with a new BLS key and proof of possession drawn from r.
+// The staking cert and key, and so the NodeID, are unchanged.
+func RotateBLS(n models.Node, r io.Reader) (models.Node, error) {
+	if err := addBLSKey(r, &n); err != nil {
+		return models.Node{}, err
+	}
+	return n, nil
 }
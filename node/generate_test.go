@@ -1,6 +1,7 @@
 package node
 
 import (
+	"os"
 	"testing"
 
 	"github.com/multisig-labs/tartarus/utils"
@@ -36,6 +37,15 @@ func TestGenerate(t *testing.T) {
 		t.Fatal("BLSSignature is empty")
 	}
 
+	if problems := Validate(n); len(problems) > 0 {
+		t.Fatalf("generated node is invalid: %v", problems)
+	}
+
+	// The API check needs network access, so it only runs when asked for; the default run works offline.
+	if os.Getenv("TARTARUS_NETWORK_TESTS") != "1" {
+		return
+	}
+
 	// test the node against the API
 	valid, err := utils.VerifyBLSViaAPI(n.NodeID, n.BLSPublicKey, n.BLSSignature)
 	if err != nil {
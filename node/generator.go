@@ -0,0 +1,223 @@
+package node
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"io"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/multisig-labs/tartarus/models"
+)
+
+// Matcher reports whether a NodeID (including the "NodeID-" prefix) is wanted.
+type Matcher func(nodeID string) bool
+
+// MatchAffixes returns a Matcher for NodeIDs whose part after "NodeID-" starts with prefix
+// and ends with suffix. Unless caseSensitive is set, both are compared case-insensitively.
+func MatchAffixes(prefix, suffix string, caseSensitive bool) Matcher {
+	if !caseSensitive {
+		prefix, suffix = strings.ToLower(prefix), strings.ToLower(suffix)
+	}
+	return func(nodeID string) bool {
+		id := strings.TrimPrefix(nodeID, "NodeID-")
+		if !caseSensitive {
+			id = strings.ToLower(id)
+		}
+		return strings.HasPrefix(id, prefix) && strings.HasSuffix(id, suffix)
+	}
+}
+
+// Progress is reported periodically while a Generator runs.
+type Progress struct {
+	Attempts uint64        // Staking certs generated so far
+	Found    int           // Matching nodes found so far
+	Elapsed  time.Duration // Time since Generate was called
+}
+
+// Option configures a Generator.
+type Option func(*Generator)
+
+// WithWorkers sets the number of goroutines generating certs. The default is runtime.NumCPU().
+func WithWorkers(n int) Option {
+	return func(g *Generator) { g.workers = n }
+}
+
+// WithMatcher only yields nodes whose NodeID matches m. By default every node matches.
+func WithMatcher(m Matcher) Option {
+	return func(g *Generator) { g.matcher = m }
+}
+
+// WithBLS controls whether a BLS key and proof of possession are generated for each node.
+// It is on by default. BLS keys are only generated for matching NodeIDs, so it doesn't slow the search.
+func WithBLS(enabled bool) Option {
+	return func(g *Generator) { g.bls = enabled }
+}
+
+// WithRand sets the source of randomness for staking and BLS keys. The default is crypto/rand.Reader.
+// The reader is shared by all workers and must be safe for concurrent use. Output is only
+// reproducible with a single worker, since otherwise the order workers read in varies.
+func WithRand(r io.Reader) Option {
+	return func(g *Generator) { g.rand = r }
+}
+
+// WithProgress calls fn every interval while Generate runs, from a single goroutine.
+// fn is not called after the channel returned by Generate is closed.
+func WithProgress(interval time.Duration, fn func(Progress)) Option {
+	return func(g *Generator) {
+		g.progressInterval = interval
+		g.progress = fn
+	}
+}
+
+// Generator searches for nodes whose NodeID satisfies a Matcher.
+type Generator struct {
+	workers          int
+	matcher          Matcher
+	bls              bool
+	rand             io.Reader
+	progressInterval time.Duration
+	progress         func(Progress)
+
+	mu  sync.Mutex
+	err error
+}
+
+// NewGenerator returns a Generator configured by opts.
+func NewGenerator(opts ...Option) *Generator {
+	g := &Generator{workers: runtime.NumCPU(), bls: true, rand: rand.Reader}
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g
+}
+
+// Generate starts searching for n matching nodes and returns a channel they are delivered on.
+// The channel is closed once n nodes were delivered, ctx is done or generation failed;
+// Err reports why it closed early. Generate returns an error only for an invalid configuration.
+func (g *Generator) Generate(ctx context.Context, n int) (<-chan models.Node, error) {
+	switch {
+	case n < 0:
+		return nil, errors.New("node count must not be negative")
+	case g.workers <= 0:
+		return nil, errors.New("worker count must be positive")
+	case g.rand == nil:
+		return nil, errors.New("no randomness source")
+	case g.progress != nil && g.progressInterval <= 0:
+		return nil, errors.New("progress interval must be positive")
+	}
+	g.setErr(nil)
+
+	ctx, cancel := context.WithCancel(ctx)
+	out := make(chan models.Node)
+	matches := make(chan models.Node)
+	var attempts atomic.Uint64
+	var found atomic.Int64
+
+	var wg sync.WaitGroup
+	for w := 0; w < g.workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			g.work(ctx, cancel, matches, &attempts)
+		}()
+	}
+
+	if g.progress != nil {
+		start := time.Now()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ticker := time.NewTicker(g.progressInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					g.progress(Progress{Attempts: attempts.Load(), Found: int(found.Load()), Elapsed: time.Since(start)})
+				}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(out)
+		defer wg.Wait()
+		defer cancel()
+		for found.Load() < int64(n) {
+			select {
+			case <-ctx.Done():
+				if g.Err() == nil && ctx.Err() != nil {
+					g.setErr(context.Cause(ctx))
+				}
+				return
+			case m := <-matches:
+				select {
+				case out <- m:
+					found.Add(1)
+				case <-ctx.Done():
+				}
+			}
+		}
+	}()
+	return out, nil
+}
+
+// work generates certs until ctx is done, passing matching nodes to matches.
+func (g *Generator) work(ctx context.Context, cancel context.CancelFunc, matches chan<- models.Node, attempts *atomic.Uint64) {
+	for ctx.Err() == nil {
+		n, err := newIdentity(g.rand, time.Now())
+		if err != nil {
+			g.fail(ctx, err, cancel)
+			return
+		}
+		attempts.Add(1)
+		if g.matcher != nil && !g.matcher(n.NodeID) {
+			continue
+		}
+		if g.bls {
+			if err := addBLSKey(g.rand, &n); err != nil {
+				g.fail(ctx, err, cancel)
+				return
+			}
+		}
+		select {
+		case matches <- n:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Err returns the error that ended the most recent Generate call early, or nil.
+// It is only meaningful once the channel returned by Generate has been closed.
+func (g *Generator) Err() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.err
+}
+
+func (g *Generator) setErr(err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.err = err
+}
+
+// fail records the first generation error and stops the other workers.
+// Errors after the run was already over are not reported.
+func (g *Generator) fail(ctx context.Context, err error, cancel context.CancelFunc) {
+	if ctx.Err() != nil {
+		return
+	}
+	g.mu.Lock()
+	if g.err == nil {
+		g.err = err
+	}
+	g.mu.Unlock()
+	cancel()
+}
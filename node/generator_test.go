@@ -0,0 +1,95 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGeneratorMatcherAndCount(t *testing.T) {
+	g := NewGenerator(WithWorkers(2), WithMatcher(MatchAffixes("A", "", false)))
+	results, err := g.Generate(context.Background(), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	count := 0
+	for n := range results {
+		count++
+		if !strings.HasPrefix(strings.ToLower(n.NodeID), "nodeid-a") {
+			t.Errorf("%s does not match the prefix", n.NodeID)
+		}
+		if problems := Validate(n); len(problems) > 0 {
+			t.Errorf("%s is invalid: %v", n.NodeID, problems)
+		}
+	}
+	if count != 3 {
+		t.Fatalf("expected 3 nodes, got %d", count)
+	}
+	if err := g.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGeneratorWithoutBLS(t *testing.T) {
+	results, err := NewGenerator(WithWorkers(1), WithBLS(false)).Generate(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := <-results
+	if n.NodeID == "" || n.Cert == "" || n.Key == "" {
+		t.Fatalf("missing staking identity: %+v", n)
+	}
+	if n.BLSPrivateKey != "" || n.BLSPublicKey != "" || n.BLSSignature != "" {
+		t.Fatal("BLS key generated although disabled")
+	}
+}
+
+func TestGeneratorCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var reported bool
+	g := NewGenerator(
+		WithWorkers(2),
+		WithMatcher(func(string) bool { return false }),
+		WithProgress(10*time.Millisecond, func(p Progress) {
+			if p.Attempts > 0 {
+				reported = true
+			}
+		}),
+	)
+	results, err := g.Generate(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	for range results {
+		t.Fatal("no node should match")
+	}
+	if !errors.Is(g.Err(), context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", g.Err())
+	}
+	if !reported {
+		t.Error("progress was never reported")
+	}
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) { return 0, errors.New("entropy source unavailable") }
+
+func TestGeneratorRandError(t *testing.T) {
+	g := NewGenerator(WithWorkers(2), WithRand(failingReader{}))
+	results, err := g.Generate(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range results {
+		t.Fatal("no node can be generated without entropy")
+	}
+	if g.Err() == nil || !strings.Contains(g.Err().Error(), "entropy source unavailable") {
+		t.Fatalf("expected the reader error, got %v", g.Err())
+	}
+}
@@ -0,0 +1,192 @@
+package node
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/multisig-labs/tartarus/models"
+)
+
+// Input formats understood by ReadNodes.
+const (
+	FormatJSON        = "json"         // {"nodes": [...]}, as written by generate -o nodes.json
+	FormatNDJSON      = "ndjson"       // One node object per line
+	FormatCSV         = "csv"          // The header and columns generate -o nodes.csv writes
+	FormatStakingDirs = "staking-dirs" // A tree of staker.crt/staker.key/signer.key directories
+)
+
+// csvColumns maps the CSV header names generate writes to the node field they hold.
+// node_id is accepted as well, to match the JSON field name.
+var csvColumns = map[string]func(n *models.Node) *string{
+	"nodeID":          func(n *models.Node) *string { return &n.NodeID },
+	"node_id":         func(n *models.Node) *string { return &n.NodeID },
+	"cert":            func(n *models.Node) *string { return &n.Cert },
+	"key":             func(n *models.Node) *string { return &n.Key },
+	"bls_private":     func(n *models.Node) *string { return &n.BLSPrivateKey },
+	"bls_public":      func(n *models.Node) *string { return &n.BLSPublicKey },
+	"bls_signature":   func(n *models.Node) *string { return &n.BLSSignature },
+	"active_provider": func(n *models.Node) *string { return &n.ActiveProvider },
+}
+
+// DetectFormat works out the format of path from its contents rather than its extension,
+// so a renamed file is still read correctly.
+func DetectFormat(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() {
+		return FormatStakingDirs, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return detectFormat(data), nil
+}
+
+// detectFormat decides on the first non-space byte. Both JSON and NDJSON start with an object,
+// so for those the whole document is parsed: a JSON file is a single object with a "nodes" key,
+// wherever that key is, and anything else is one object per line.
+func detectFormat(data []byte) string {
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case len(trimmed) == 0:
+		return FormatJSON
+	case trimmed[0] == '[':
+		return FormatJSON
+	case trimmed[0] != '{':
+		return FormatCSV
+	}
+
+	var doc map[string]json.RawMessage
+	dec := json.NewDecoder(bytes.NewReader(trimmed))
+	if err := dec.Decode(&doc); err != nil {
+		// Broken either way. If the first line holds a complete object, the file is NDJSON and
+		// its parser reports the bad line; otherwise the JSON parser reports the error.
+		firstLine, _, _ := bytes.Cut(trimmed, []byte("\n"))
+		if json.Valid(firstLine) {
+			return FormatNDJSON
+		}
+		return FormatJSON
+	}
+	if _, ok := doc["nodes"]; ok && !dec.More() {
+		return FormatJSON
+	}
+	return FormatNDJSON
+}
+
+// ReadNodes loads nodes from path in any of the supported formats.
+func ReadNodes(path string) ([]models.Node, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return ReadStakingDirs(path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	switch detectFormat(data) {
+	case FormatNDJSON:
+		return parseNDJSON(data)
+	case FormatCSV:
+		return parseCSV(data)
+	default:
+		return parseJSON(data)
+	}
+}
+
+// parseJSON reads the {"nodes": [...]} shape, or a bare array of nodes.
+func parseJSON(data []byte) ([]models.Node, error) {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		var nodes []models.Node
+		if err := json.Unmarshal(trimmed, &nodes); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+		return nodes, nil
+	}
+
+	var nodeContainer struct {
+		Nodes []models.Node `json:"nodes"`
+	}
+	if err := json.Unmarshal(data, &nodeContainer); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	return nodeContainer.Nodes, nil
+}
+
+// parseNDJSON reads one node object per line, skipping blank lines.
+func parseNDJSON(data []byte) ([]models.Node, error) {
+	var nodes []models.Node
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	// Certs and keys make lines far longer than the default 64 KiB token limit allows for.
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		var n models.Node
+		if err := json.Unmarshal(text, &n); err != nil {
+			return nil, fmt.Errorf("line %d: invalid JSON: %w", line, err)
+		}
+		nodes = append(nodes, n)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nodes, nil
+}
+
+// parseCSV reads the CSV generate writes. Columns are matched by header name, so their order doesn't matter.
+func parseCSV(data []byte) ([]models.Node, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	header, err := r.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV header: %w", err)
+	}
+
+	fields := make([]func(n *models.Node) *string, len(header))
+	hasNodeID := false
+	for i, name := range header {
+		name = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+		fields[i] = csvColumns[name]
+		hasNodeID = hasNodeID || name == "nodeID" || name == "node_id"
+	}
+	if !hasNodeID {
+		return nil, fmt.Errorf("CSV header has no nodeID column (got %s)", strings.Join(header, ","))
+	}
+
+	var nodes []models.Node
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return nodes, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+		var n models.Node
+		for i, value := range record {
+			if fields[i] != nil {
+				*fields[i](&n) = value
+			}
+		}
+		nodes = append(nodes, n)
+	}
+}
@@ -0,0 +1,114 @@
+package node
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/multisig-labs/tartarus/models"
+)
+
+func TestReadNodes(t *testing.T) {
+	var nodes []models.Node
+	for i := 0; i < 2; i++ {
+		n, err := Generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		nodes = append(nodes, n)
+	}
+
+	dir := t.TempDir()
+	write := func(name string, data []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	jsonData, err := json.MarshalIndent(map[string][]models.Node{"nodes": nodes}, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Pretty-printed, with "nodes" after another key.
+	laterKeyData, err := json.MarshalIndent(struct {
+		Network string        `json:"network"`
+		Nodes   []models.Node `json:"nodes"`
+	}{"fuji", nodes}, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ndjson strings.Builder
+	for _, n := range nodes {
+		line, err := json.Marshal(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ndjson.Write(line)
+		ndjson.WriteString("\n\n")
+	}
+
+	// Same header and columns as runGenerateCommand writes.
+	var csvData strings.Builder
+	w := csv.NewWriter(&csvData)
+	w.Write([]string{"nodeID", "cert", "key", "bls_private", "bls_public", "bls_signature", "active_provider"})
+	for _, n := range nodes {
+		w.Write([]string{n.NodeID, n.Cert, n.Key, n.BLSPrivateKey, n.BLSPublicKey, n.BLSSignature, n.ActiveProvider})
+	}
+	w.Flush()
+
+	cases := map[string]struct {
+		path   string
+		format string
+	}{
+		"json":              {write("nodes.json", jsonData), FormatJSON},
+		"json nodes later":  {write("later.json", laterKeyData), FormatJSON},
+		"ndjson":            {write("nodes.ndjson", []byte(ndjson.String())), FormatNDJSON},
+		"csv":               {write("nodes.csv", []byte(csvData.String())), FormatCSV},
+		"csv named .json":   {write("renamed.json", []byte(csvData.String())), FormatCSV},
+		"ndjson named .txt": {write("nodes.txt", []byte(ndjson.String())), FormatNDJSON},
+	}
+	for name, tc := range cases {
+		format, err := DetectFormat(tc.path)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if format != tc.format {
+			t.Errorf("%s: detected %s, expected %s", name, format, tc.format)
+		}
+
+		got, err := ReadNodes(tc.path)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(got) != len(nodes) {
+			t.Fatalf("%s: read %d nodes, expected %d", name, len(got), len(nodes))
+		}
+		for i := range nodes {
+			if got[i] != nodes[i] {
+				t.Errorf("%s: node %d differs after reading", name, i)
+			}
+		}
+	}
+
+	firstLine, err := json.Marshal(nodes[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format := detectFormat(firstLine); format != FormatNDJSON {
+		t.Errorf("a single NDJSON line was detected as %s", format)
+	}
+	if _, err := ReadNodes(write("broken.ndjson", append(append(firstLine, '\n'), `{"nodeID": `...))); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("expected the broken NDJSON line to be reported, got %v", err)
+	}
+
+	if _, err := ReadNodes(write("bad.csv", []byte("a,b\n1,2\n"))); err == nil {
+		t.Error("expected a CSV without a nodeID column to be rejected")
+	}
+}
@@ -0,0 +1,46 @@
+package node
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/multisig-labs/tartarus/models"
+)
+
+// csvHeader is the header generate writes and parseCSV reads.
+var csvHeader = []string{"nodeID", "cert", "key", "bls_private", "bls_public", "bls_signature", "active_provider"}
+
+// WriteNodes writes nodes to w in FormatJSON, FormatNDJSON or FormatCSV, so that ReadNodes reads them back.
+func WriteNodes(w io.Writer, format string, nodes []models.Node) error {
+	switch format {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(map[string][]models.Node{"nodes": nodes})
+	case FormatNDJSON:
+		enc := json.NewEncoder(w)
+		for _, n := range nodes {
+			if err := enc.Encode(n); err != nil {
+				return err
+			}
+		}
+		return nil
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(csvHeader); err != nil {
+			return err
+		}
+		for _, n := range nodes {
+			record := []string{n.NodeID, n.Cert, n.Key, n.BLSPrivateKey, n.BLSPublicKey, n.BLSSignature, n.ActiveProvider}
+			if err := cw.Write(record); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	default:
+		return fmt.Errorf("unsupported format %q", format)
+	}
+}
@@ -0,0 +1,127 @@
+package node
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/staking"
+
+	"github.com/multisig-labs/tartarus/models"
+)
+
+// File names avalanchego expects in a staking directory.
+const (
+	StakerCertFile = "staker.crt"
+	StakerKeyFile  = "staker.key"
+	SignerKeyFile  = "signer.key"
+)
+
+// FromKeys rebuilds a node from its PEM encoded staking cert and key and its raw BLS secret key,
+// deriving the NodeID, BLS public key and proof of possession.
+func FromKeys(certBytes, keyBytes, blsPrivateBytes []byte) (models.Node, error) {
+	tlsCert, err := staking.LoadTLSCertFromBytes(keyBytes, certBytes)
+	if err != nil {
+		return models.Node{}, err
+	}
+
+	stakingCert, err := staking.ParseCertificate(tlsCert.Leaf.Raw)
+	if err != nil {
+		return models.Node{}, err
+	}
+
+	blsKey, err := BLSKeyFromSecret(blsPrivateBytes)
+	if err != nil {
+		return models.Node{}, err
+	}
+
+	return models.Node{
+		NodeID:        ids.NodeIDFromCert(stakingCert).String(),
+		Cert:          string(certBytes),
+		Key:           string(keyBytes),
+		BLSPrivateKey: hex.EncodeToString(blsKey.Secret),
+		BLSPublicKey:  hex.EncodeToString(blsKey.PublicKey),
+		BLSSignature:  hex.EncodeToString(blsKey.ProofOfPossession),
+	}, nil
+}
+
+// NodeIDFromCertPEM returns the NodeID of a PEM encoded staking certificate.
+func NodeIDFromCertPEM(certPEM string) (string, error) {
+	cert, err := ParseCertPEM(certPEM)
+	if err != nil {
+		return "", err
+	}
+	return ids.NodeIDFromCert(cert).String(), nil
+}
+
+// FromStakingDir rebuilds a node from a directory containing staker.crt, staker.key and signer.key.
+func FromStakingDir(dir string) (models.Node, error) {
+	certBytes, err := os.ReadFile(filepath.Join(dir, StakerCertFile))
+	if err != nil {
+		return models.Node{}, err
+	}
+	keyBytes, err := os.ReadFile(filepath.Join(dir, StakerKeyFile))
+	if err != nil {
+		return models.Node{}, err
+	}
+	blsPrivateBytes, err := os.ReadFile(filepath.Join(dir, SignerKeyFile))
+	if err != nil {
+		return models.Node{}, err
+	}
+
+	n, err := FromKeys(certBytes, keyBytes, blsPrivateBytes)
+	if err != nil {
+		return models.Node{}, fmt.Errorf("%s: %w", dir, err)
+	}
+	return n, nil
+}
+
+// IsStakingDir reports whether dir contains a staking certificate.
+func IsStakingDir(dir string) bool {
+	info, err := os.Stat(filepath.Join(dir, StakerCertFile))
+	return err == nil && !info.IsDir()
+}
+
+// StakingDirs returns every staking directory under root, including root itself,
+// in lexical order of their paths.
+func StakingDirs(root string) ([]string, error) {
+	var dirs []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && IsStakingDir(path) {
+			dirs = append(dirs, path)
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(dirs)
+	return dirs, nil
+}
+
+// ReadStakingDirs loads every staking directory under root, including root itself,
+// in lexical order of their paths.
+func ReadStakingDirs(root string) ([]models.Node, error) {
+	dirs, err := StakingDirs(root)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make([]models.Node, 0, len(dirs))
+	for _, dir := range dirs {
+		n, err := FromStakingDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes, nil
+}
@@ -0,0 +1,45 @@
+package node
+
+import (
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadStakingDirs(t *testing.T) {
+	n, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root := t.TempDir()
+	dir := filepath.Join(root, n.NodeID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	blsPrivateBytes, err := hex.DecodeString(n.BLSPrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string][]byte{
+		StakerCertFile: []byte(n.Cert),
+		StakerKeyFile:  []byte(n.Key),
+		SignerKeyFile:  blsPrivateBytes,
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	nodes, err := ReadStakingDirs(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 1 {
+		t.Fatalf("expected 1 node, got %d", len(nodes))
+	}
+	if nodes[0] != n {
+		t.Fatalf("node read back from staking dir differs:\n got %+v\nwant %+v", nodes[0], n)
+	}
+}
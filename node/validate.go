@@ -0,0 +1,163 @@
+package node
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/staking"
+	"github.com/ava-labs/avalanchego/utils/crypto/bls"
+
+	"github.com/multisig-labs/tartarus/models"
+)
+
+// ErrBLSPrivateKey is wrapped by every problem Validate finds with a node's BLS private key.
+var ErrBLSPrivateKey = errors.New("BLS private key")
+
+// decodeHex decodes a hex string with or without a 0x prefix.
+func decodeHex(s string) ([]byte, error) {
+	return hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(s), "0x"))
+}
+
+// ParseCertPEM parses a PEM encoded staking certificate.
+func ParseCertPEM(certPEM string) (*staking.Certificate, error) {
+	block, _ := pem.Decode([]byte(certPEM))
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	if block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("unexpected PEM block type %q", block.Type)
+	}
+	return staking.ParseCertificate(block.Bytes)
+}
+
+// ParseStakingKeyPEM parses a PEM encoded private key in PKCS #8, SEC 1 or PKCS #1 form.
+func ParseStakingKeyPEM(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	var key interface{}
+	var err error
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported key type %T", key)
+	}
+	return signer, nil
+}
+
+// ParseNodeID checks that s is a NodeID and returns it in canonical form.
+func ParseNodeID(s string) (string, error) {
+	nodeID, err := ids.NodeIDFromString(strings.TrimSpace(s))
+	if err != nil {
+		return "", err
+	}
+	return nodeID.String(), nil
+}
+
+// NodeIDBytes returns the 20 raw bytes of a NodeID, the form contracts and L1 messages use.
+func NodeIDBytes(s string) ([]byte, error) {
+	nodeID, err := ids.NodeIDFromString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, err
+	}
+	return nodeID.Bytes(), nil
+}
+
+// VerifyProofOfPossession checks that a hex encoded BLS signature is a valid proof of
+// possession for a hex encoded compressed BLS public key.
+func VerifyProofOfPossession(publicKeyHex, signatureHex string) error {
+	pkBytes, err := decodeHex(publicKeyHex)
+	if err != nil {
+		return fmt.Errorf("BLS public key is not valid hex: %w", err)
+	}
+	if len(pkBytes) != bls.PublicKeyLen {
+		return fmt.Errorf("BLS public key is %d bytes, expected %d", len(pkBytes), bls.PublicKeyLen)
+	}
+	pk, err := bls.PublicKeyFromCompressedBytes(pkBytes)
+	if err != nil {
+		return fmt.Errorf("BLS public key is not a valid compressed G1 point: %w", err)
+	}
+
+	sigBytes, err := decodeHex(signatureHex)
+	if err != nil {
+		return fmt.Errorf("BLS signature is not valid hex: %w", err)
+	}
+	if len(sigBytes) != bls.SignatureLen {
+		return fmt.Errorf("BLS signature is %d bytes, expected %d", len(sigBytes), bls.SignatureLen)
+	}
+	sig, err := bls.SignatureFromBytes(sigBytes)
+	if err != nil {
+		return fmt.Errorf("BLS signature is not a valid G2 point: %w", err)
+	}
+
+	if !bls.VerifyProofOfPossession(pk, sig, pkBytes) {
+		return errors.New("BLS signature is not a valid proof of possession for the public key")
+	}
+	return nil
+}
+
+// Validate checks a node record offline and returns every problem found.
+// The NodeID and BLS fields are always required; the cert, staking key and BLS private key
+// are checked against them when present.
+func Validate(n models.Node) []error {
+	var problems []error
+
+	nodeID, err := ids.NodeIDFromString(n.NodeID)
+	if err != nil {
+		problems = append(problems, fmt.Errorf("invalid NodeID %q: %w", n.NodeID, err))
+	}
+
+	if err := VerifyProofOfPossession(n.BLSPublicKey, n.BLSSignature); err != nil {
+		problems = append(problems, err)
+	}
+
+	var cert *staking.Certificate
+	if n.Cert != "" {
+		cert, err = ParseCertPEM(n.Cert)
+		if err != nil {
+			problems = append(problems, fmt.Errorf("invalid staking cert: %w", err))
+		} else if certNodeID := ids.NodeIDFromCert(cert); nodeID != ids.EmptyNodeID && certNodeID != nodeID {
+			problems = append(problems, fmt.Errorf("staking cert hashes to %s, not %s", certNodeID, n.NodeID))
+		}
+	}
+
+	if n.Key != "" {
+		key, err := ParseStakingKeyPEM([]byte(n.Key))
+		if err != nil {
+			problems = append(problems, fmt.Errorf("invalid staking key: %w", err))
+		} else if pub, ok := key.Public().(interface{ Equal(crypto.PublicKey) bool }); cert != nil && (!ok || !pub.Equal(cert.PublicKey)) {
+			problems = append(problems, errors.New("staking key does not belong to the staking cert"))
+		}
+	}
+
+	if n.BLSPrivateKey != "" {
+		skBytes, err := decodeHex(n.BLSPrivateKey)
+		if err != nil {
+			problems = append(problems, fmt.Errorf("%w is not valid hex: %w", ErrBLSPrivateKey, err))
+		} else if sk, err := bls.SecretKeyFromBytes(skBytes); err != nil {
+			problems = append(problems, fmt.Errorf("invalid %w: %w", ErrBLSPrivateKey, err))
+		} else if pkBytes, err := decodeHex(n.BLSPublicKey); err == nil && !bytes.Equal(bls.PublicKeyToCompressedBytes(bls.PublicFromSecretKey(sk)), pkBytes) {
+			problems = append(problems, fmt.Errorf("%w does not match the BLS public key", ErrBLSPrivateKey))
+		}
+	}
+
+	return problems
+}
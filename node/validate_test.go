@@ -0,0 +1,73 @@
+package node
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/multisig-labs/tartarus/models"
+)
+
+func TestValidate(t *testing.T) {
+	n, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if problems := Validate(n); len(problems) != 0 {
+		t.Fatalf("freshly generated node is invalid: %v", problems)
+	}
+
+	// The 0x prefix the backend uses is accepted.
+	prefixed := n
+	prefixed.BLSPublicKey = "0x" + n.BLSPublicKey
+	prefixed.BLSSignature = "0x" + n.BLSSignature
+	if problems := Validate(prefixed); len(problems) != 0 {
+		t.Fatalf("0x prefixed node is invalid: %v", problems)
+	}
+
+	other, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]func(n *models.Node){
+		"bad checksum":       func(n *models.Node) { n.NodeID = n.NodeID[:len(n.NodeID)-1] + "x" },
+		"empty public key":   func(n *models.Node) { n.BLSPublicKey = "" },
+		"short signature":    func(n *models.Node) { n.BLSSignature = n.BLSSignature[:10] },
+		"swapped signature":  func(n *models.Node) { n.BLSSignature = other.BLSSignature },
+		"cert of other node": func(n *models.Node) { n.Cert = other.Cert },
+		"mismatched bls key": func(n *models.Node) { n.BLSPrivateKey = other.BLSPrivateKey },
+		"garbage cert":       func(n *models.Node) { n.Cert = "not a cert" },
+		"swapped staker key": func(n *models.Node) { n.Key = other.Key },
+		"garbage staker key": func(n *models.Node) { n.Key = "not a key" },
+		"uppercase node id":  func(n *models.Node) { n.NodeID = strings.ToUpper(n.NodeID) },
+	}
+	for name, corrupt := range cases {
+		bad := n
+		corrupt(&bad)
+		if problems := Validate(bad); len(problems) == 0 {
+			t.Errorf("%s: expected validation to fail", name)
+		}
+	}
+
+	// Problems with the BLS private key wrap ErrBLSPrivateKey, and no others do.
+	blsCases := map[string]string{
+		"not hex":    "zz",
+		"wrong size": "abcd",
+		"mismatched": other.BLSPrivateKey,
+	}
+	for name, sk := range blsCases {
+		bad := n
+		bad.BLSPrivateKey = sk
+		bad.BLSSignature = other.BLSSignature
+		var blsProblems int
+		for _, p := range Validate(bad) {
+			if errors.Is(p, ErrBLSPrivateKey) {
+				blsProblems++
+			}
+		}
+		if blsProblems != 1 {
+			t.Errorf("%s: expected one ErrBLSPrivateKey problem, got %d", name, blsProblems)
+		}
+	}
+}
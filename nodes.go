@@ -0,0 +1,184 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/jxskiss/mcli"
+)
+
+// --- Nodes Command Functionality ---
+
+// NodesListArgs defines the arguments for the 'nodes list' subcommand.
+type NodesListArgs struct {
+	Network            string `cli:"--network, Only show nodes on this network (e.g., fuji, mainnet)"`
+	L1ID               string `cli:"-L, --l1-id, Only show nodes assigned to this L1 ID"`
+	HWStatus           string `cli:"--hw-status, Only show nodes with this hardware status (e.g., inactive, active)"`
+	NodeState          string `cli:"--node-state, Only show nodes in this state (e.g., available, assigned)"`
+	HardwareProviderID int    `cli:"--hp-id, Only show nodes of this Hardware Provider ID"`
+	Limit              int    `cli:"--limit, Maximum number of nodes to return (0 for all)" default:"0"`
+	PageSize           int    `cli:"--page-size, Number of nodes to fetch per request" default:"1000"`
+	Format             string `cli:"-f, --format, Output format: table, json or csv" default:"table"`
+	AuthArgs
+}
+
+// NodesShowArgs defines the arguments for the 'nodes show' subcommand.
+type NodesShowArgs struct {
+	NodeID string `cli:"node-id, NodeID of the node to show"`
+	Format string `cli:"-f, --format, Output format: table, json or csv" default:"table"`
+	AuthArgs
+}
+
+// nodeRowFilters builds PostgREST eq filters for every non-empty value.
+func nodeRowFilters(network, l1ID, hwStatus, nodeState string, hardwareProviderID int) url.Values {
+	filters := url.Values{}
+	if network != "" {
+		filters.Set("network", "eq."+network)
+	}
+	if l1ID != "" {
+		filters.Set("l1_id", "eq."+l1ID)
+	}
+	if hwStatus != "" {
+		filters.Set("hw_status", "eq."+hwStatus)
+	}
+	if nodeState != "" {
+		filters.Set("node_state", "eq."+nodeState)
+	}
+	if hardwareProviderID != 0 {
+		filters.Set("hardware_provider_id", "eq."+strconv.Itoa(hardwareProviderID))
+	}
+	return filters
+}
+
+// writeNodeRows renders rows in the requested format.
+func writeNodeRows(w io.Writer, rows []NodeTableRow, format string) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if rows == nil {
+			rows = []NodeTableRow{}
+		}
+		return enc.Encode(rows)
+	case "csv":
+		cw := csv.NewWriter(w)
+		header := []string{"node_id", "network", "l1_id", "hw_status", "node_state", "hardware_provider_id", "user_id", "bls_public_key", "bls_signature"}
+		if err := cw.Write(header); err != nil {
+			return err
+		}
+		for _, r := range rows {
+			record := []string{r.NodeID, r.Network, r.L1ID, r.HWStatus, r.NodeState, strconv.Itoa(r.HardwareProviderID), r.UserID, r.BLSPublicKey, r.BLSSignature}
+			if err := cw.Write(record); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	case "table", "":
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "NODE ID\tNETWORK\tL1 ID\tHW STATUS\tNODE STATE\tHP ID")
+		for _, r := range rows {
+			l1ID := r.L1ID
+			if l1ID == "" {
+				l1ID = "-"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%d\n", r.NodeID, r.Network, l1ID, r.HWStatus, r.NodeState, r.HardwareProviderID)
+		}
+		return tw.Flush()
+	default:
+		return fmt.Errorf("unsupported format %q (expected table, json or csv)", format)
+	}
+}
+
+// writeNodeRowDetail prints every public field of a single row, one per line.
+func writeNodeRowDetail(w io.Writer, r NodeTableRow) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Node ID:\t%s\n", r.NodeID)
+	fmt.Fprintf(tw, "Network:\t%s\n", r.Network)
+	fmt.Fprintf(tw, "L1 ID:\t%s\n", r.L1ID)
+	fmt.Fprintf(tw, "HW status:\t%s\n", r.HWStatus)
+	fmt.Fprintf(tw, "Node state:\t%s\n", r.NodeState)
+	fmt.Fprintf(tw, "Hardware provider ID:\t%d\n", r.HardwareProviderID)
+	fmt.Fprintf(tw, "User ID:\t%s\n", r.UserID)
+	fmt.Fprintf(tw, "BLS public key:\t%s\n", r.BLSPublicKey)
+	fmt.Fprintf(tw, "BLS signature:\t%s\n", r.BLSSignature)
+	return tw.Flush()
+}
+
+// sessionForCommand parses the command's flags, applies the profile and authenticates,
+// exiting on any failure. It is shared by the commands that read or write the nodes table.
+func sessionForCommand(command string, args interface{}, authArgs *AuthArgs) (*authSession, profile) {
+	prof := parseAuthArgs(command, args, authArgs)
+
+	session, err := getOrRequestAccessToken(authArgs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error getting access token: %v\n", err)
+		os.Exit(1)
+	}
+	return session, prof
+}
+
+// runNodesListCommand is the handler for the "nodes list" subcommand.
+func runNodesListCommand() {
+	var listArgs NodesListArgs
+	session, _ := sessionForCommand("nodes list", &listArgs, &listArgs.AuthArgs)
+
+	filters := nodeRowFilters(listArgs.Network, listArgs.L1ID, listArgs.HWStatus, listArgs.NodeState, listArgs.HardwareProviderID)
+	rows, err := newRestClient(session).fetchNodeRows(filters, listArgs.PageSize, listArgs.Limit)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing nodes: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := writeNodeRows(os.Stdout, rows, listArgs.Format); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing nodes: %v\n", err)
+		os.Exit(1)
+	}
+	if listArgs.Format == "table" {
+		fmt.Fprintf(os.Stderr, "%d nodes\n", len(rows))
+	}
+}
+
+// runNodesShowCommand is the handler for the "nodes show" subcommand.
+func runNodesShowCommand() {
+	var showArgs NodesShowArgs
+	parseAuthArgs("nodes show", &showArgs, &showArgs.AuthArgs)
+	if showArgs.NodeID == "" {
+		fmt.Fprintln(os.Stderr, "Error: a NodeID is required.")
+		mcli.PrintHelp()
+		os.Exit(1)
+	}
+
+	session, err := getOrRequestAccessToken(&showArgs.AuthArgs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error getting access token: %v\n", err)
+		os.Exit(1)
+	}
+
+	row, err := newRestClient(session).fetchNodeRow(showArgs.NodeID)
+	if errors.Is(err, errNodeNotFound) {
+		fmt.Fprintf(os.Stderr, "Node %s not found.\n", showArgs.NodeID)
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error fetching node: %v\n", err)
+		os.Exit(1)
+	}
+
+	if showArgs.Format == "table" {
+		err = writeNodeRowDetail(os.Stdout, row)
+	} else {
+		err = writeNodeRows(os.Stdout, []NodeTableRow{row}, showArgs.Format)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing node: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// NodesRemoveArgs defines the arguments for the 'nodes remove' subcommand.
+type NodesRemoveArgs struct {
+	Hard       bool     `cli:"--hard, Delete the rows instead of setting node_state to retired"`
+	Force      bool     `cli:"--force, With --hard, also delete nodes that are currently assigned"`
+	Yes        bool     `cli:"-y, --yes, Skip the confirmation prompt"`
+	File       string   `cli:"-d, --data-file, File with NodeIDs to remove: a nodes file, staking directories tree or one NodeID per line"`
+	ReceiptDir string   `cli:"--receipt-dir, Directory to write the removal receipt to" default:"."`
+	ChunkSize  int      `cli:"--batch-size, Number of nodes to remove per request" default:"100"`
+	NodeIDs    []string `cli:"node-ids, NodeIDs to remove"`
+	AuthArgs
+}
+
+// removalReceipt is the local audit record written after a removal.
+type removalReceipt struct {
+	Mode        string         `json:"mode"` // "retire" or "delete"
+	RemovedAt   string         `json:"removed_at"`
+	UserID      string         `json:"user_id"`
+	Profile     string         `json:"profile"`
+	SupabaseURL string         `json:"supabase_url"`
+	Nodes       []NodeTableRow `json:"nodes"`            // Rows the server confirmed removing, as they were before
+	Failed      []string       `json:"failed,omitempty"` // NodeIDs that were planned but not confirmed removed
+}
+
+// errRemovalAborted is returned by removeNodes when the removal is not confirmed.
+var errRemovalAborted = errors.New("aborted")
+
+// removalPlan is what a removal will change, worked out before anything is sent.
+type removalPlan struct {
+	Rows     []NodeTableRow // Rows to remove
+	Skipped  []string       // Nodes that are already retired
+	Problems []string       // Reasons nodes cannot be removed; any problem cancels the whole removal
+}
+
+// planRemoval checks every node against its current row. Retiring follows the node_state
+// transitions, so an assigned node has to be released first even with --force. Deleting an
+// assigned node is allowed only with force.
+func planRemoval(nodeIDs []string, current map[string]NodeTableRow, hard, force bool) removalPlan {
+	var plan removalPlan
+	for _, id := range nodeIDs {
+		row, ok := current[id]
+		switch {
+		case !ok:
+			plan.Problems = append(plan.Problems, id+": not found")
+		case hard && row.NodeState == nodeStateAssigned && !force:
+			plan.Problems = append(plan.Problems, id+": node is assigned, release it first or pass --force")
+		case hard:
+			plan.Rows = append(plan.Rows, row)
+		case row.NodeState == nodeStateRetired:
+			plan.Skipped = append(plan.Skipped, id)
+		default:
+			if err := checkTransition("node_state", nodeStateTransitions, row.NodeState, nodeStateRetired); err != nil {
+				plan.Problems = append(plan.Problems, fmt.Sprintf("%s: %v, release it first", id, err))
+				continue
+			}
+			plan.Rows = append(plan.Rows, row)
+		}
+	}
+	return plan
+}
+
+// summarizeRows prints how many rows there are per network and state, followed by the NodeIDs.
+func summarizeRows(rows []NodeTableRow) {
+	counts := map[string]int{}
+	for _, r := range rows {
+		counts[fmt.Sprintf("network=%s node_state=%s hw_status=%s", r.Network, r.NodeState, r.HWStatus)]++
+	}
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Printf("  %4d  %s\n", counts[k], k)
+	}
+
+	const maxListed = 20
+	for i, r := range rows {
+		if i == maxListed {
+			fmt.Printf("  ... and %d more\n", len(rows)-maxListed)
+			break
+		}
+		fmt.Printf("  - %s\n", r.NodeID)
+	}
+}
+
+// confirm asks the user to type "yes" on in. Anything else, including EOF, is a no.
+func confirm(in io.Reader, prompt string) bool {
+	fmt.Printf("%s Type 'yes' to continue: ", prompt)
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil {
+		fmt.Println()
+		return false
+	}
+	return strings.TrimSpace(answer) == "yes"
+}
+
+// deleteNodeRows deletes the rows for the given NodeIDs, in chunks, and returns the NodeIDs the
+// server reports as deleted.
+func (c *restClient) deleteNodeRows(nodeIDs []string, chunkSize int) ([]string, error) {
+	var deleted []string
+	for i := 0; i < len(nodeIDs); i += chunkSize {
+		end := i + chunkSize
+		if end > len(nodeIDs) {
+			end = len(nodeIDs)
+		}
+		query := url.Values{}
+		query.Set("node_id", nodeIDInFilter(nodeIDs[i:end]))
+		query.Set("select", "node_id")
+
+		status, respBody, err := c.do("DELETE", nodesTablePath, query, nil, "return=representation")
+		if err != nil {
+			return deleted, err
+		}
+		if status != http.StatusOK {
+			return deleted, fmt.Errorf("failed to delete nodes. Status: %d, Body: %s", status, string(respBody))
+		}
+
+		var rows []struct {
+			NodeID string `json:"node_id"`
+		}
+		if err := json.Unmarshal(respBody, &rows); err != nil {
+			return deleted, fmt.Errorf("failed to parse delete response JSON: %w", err)
+		}
+		for _, r := range rows {
+			deleted = append(deleted, r.NodeID)
+		}
+	}
+	return deleted, nil
+}
+
+// writeRemovalReceipt saves the receipt as removal_receipt_<timestamp>.json in dir. If a receipt
+// with that name exists already, a _2, _3, ... suffix is added rather than overwriting it.
+func writeRemovalReceipt(dir string, receipt removalReceipt) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create receipt directory: %w", err)
+	}
+	data, err := json.MarshalIndent(receipt, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal receipt: %w", err)
+	}
+
+	base := "removal_receipt_" + time.Now().UTC().Format("20060102T150405Z")
+	for i := 1; ; i++ {
+		name := base + ".json"
+		if i > 1 {
+			name = fmt.Sprintf("%s_%d.json", base, i)
+		}
+		path := filepath.Join(dir, name)
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to write receipt: %w", err)
+		}
+		_, err = f.Write(data)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to write receipt: %w", err)
+		}
+		return path, nil
+	}
+}
+
+// removeNodes shows the rows, asks for confirmation on in unless --yes was given, then removes
+// them and writes the receipt. It returns the number of rows the server reports as removed.
+func removeNodes(client *restClient, args *NodesRemoveArgs, rows []NodeTableRow, in io.Reader) (int, error) {
+	mode := "retire"
+	action := "Retire (set node_state=retired)"
+	if args.Hard {
+		mode = "delete"
+		action = "PERMANENTLY DELETE"
+	}
+	fmt.Printf("%s %d nodes:\n", action, len(rows))
+	summarizeRows(rows)
+
+	if !args.Yes && !confirm(in, "Proceed?") {
+		return 0, errRemovalAborted
+	}
+
+	toRemove := make([]string, len(rows))
+	for i, r := range rows {
+		toRemove[i] = r.NodeID
+	}
+
+	var removed []string
+	var err error
+	if args.Hard {
+		removed, err = client.deleteNodeRows(toRemove, args.ChunkSize)
+	} else {
+		removed, err = client.patchNodeRows(toRemove, map[string]string{"node_state": nodeStateRetired}, args.ChunkSize)
+	}
+
+	// Write the receipt even after a partial failure. It lists only the rows the server confirmed,
+	// so it shows exactly what changed, and the rest as failed.
+	receipt := removalReceipt{
+		Mode:        mode,
+		RemovedAt:   time.Now().UTC().Format(time.RFC3339),
+		UserID:      client.session.UserID,
+		Profile:     args.Profile,
+		SupabaseURL: args.SupabaseURL,
+		Nodes:       []NodeTableRow{},
+	}
+	confirmed := make(map[string]bool, len(removed))
+	for _, id := range removed {
+		confirmed[id] = true
+	}
+	for _, r := range rows {
+		if confirmed[r.NodeID] {
+			receipt.Nodes = append(receipt.Nodes, r)
+		} else {
+			receipt.Failed = append(receipt.Failed, r.NodeID)
+		}
+	}
+	receiptPath, receiptErr := writeRemovalReceipt(args.ReceiptDir, receipt)
+	if receiptErr != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", receiptErr)
+	} else {
+		fmt.Printf("Receipt saved to %s\n", receiptPath)
+	}
+	return len(removed), err
+}
+
+// runNodesRemoveCommand is the handler for the "nodes remove" subcommand.
+// By default nodes are retired (node_state=retired); --hard deletes the rows.
+func runNodesRemoveCommand() {
+	var removeArgs NodesRemoveArgs
+	parseAuthArgs("nodes remove", &removeArgs, &removeArgs.AuthArgs)
+	if removeArgs.Force && !removeArgs.Hard {
+		fmt.Fprintln(os.Stderr, "Error: --force only applies to --hard. Assigned nodes cannot be retired; release them first.")
+		os.Exit(1)
+	}
+	if removeArgs.ChunkSize <= 0 {
+		removeArgs.ChunkSize = 100
+	}
+
+	nodeIDs, err := collectNodeIDs(removeArgs.NodeIDs, removeArgs.File)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if len(nodeIDs) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: no NodeIDs given. Pass them as arguments or with --data-file.")
+		os.Exit(1)
+	}
+
+	session, err := getOrRequestAccessToken(&removeArgs.AuthArgs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error getting access token: %v\n", err)
+		os.Exit(1)
+	}
+	client := newRestClient(session)
+
+	current, err := client.fetchNodeRowsByID(nodeIDs, removeArgs.ChunkSize)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error fetching nodes: %v\n", err)
+		os.Exit(1)
+	}
+
+	plan := planRemoval(nodeIDs, current, removeArgs.Hard, removeArgs.Force)
+	for _, id := range plan.Skipped {
+		fmt.Fprintf(os.Stderr, "%s: already retired, skipping\n", id)
+	}
+	for _, p := range plan.Problems {
+		fmt.Fprintln(os.Stderr, p)
+	}
+	if len(plan.Problems) > 0 {
+		fmt.Fprintf(os.Stderr, "Error: %d of %d nodes cannot be removed. Nothing was changed.\n", len(plan.Problems), len(nodeIDs))
+		os.Exit(1)
+	}
+	if len(plan.Rows) == 0 {
+		fmt.Println("Nothing to remove.")
+		return
+	}
+
+	removed, err := removeNodes(client, &removeArgs, plan.Rows, os.Stdin)
+	if errors.Is(err, errRemovalAborted) {
+		fmt.Println("Aborted. Nothing was changed.")
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error removing nodes (%d removed before the error): %v\n", removed, err)
+		os.Exit(1)
+	}
+	mode := "retire"
+	if removeArgs.Hard {
+		mode = "delete"
+	}
+	fmt.Printf("Removed %d of %d nodes (%s).\n", removed, len(plan.Rows), mode)
+	if removed != len(plan.Rows) {
+		fmt.Fprintln(os.Stderr, "Warning: the server removed fewer nodes than requested. Check that you own them.")
+		os.Exit(1)
+	}
+}
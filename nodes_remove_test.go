@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPlanRemoval(t *testing.T) {
+	current := map[string]NodeTableRow{
+		"NodeID-available": {NodeID: "NodeID-available", NodeState: nodeStateAvailable},
+		"NodeID-assigned":  {NodeID: "NodeID-assigned", NodeState: nodeStateAssigned},
+		"NodeID-retired":   {NodeID: "NodeID-retired", NodeState: nodeStateRetired},
+	}
+	cases := []struct {
+		name         string
+		nodeIDs      []string
+		hard, force  bool
+		wantRows     int
+		wantSkipped  int
+		wantProblems []string
+	}{
+		{"retire available", []string{"NodeID-available"}, false, false, 1, 0, nil},
+		{"retire skips retired", []string{"NodeID-retired", "NodeID-available"}, false, false, 1, 1, nil},
+		{"retire refuses assigned", []string{"NodeID-assigned"}, false, false, 0, 0, []string{`NodeID-assigned: cannot change node_state from "assigned" to "retired"`}},
+		{"delete refuses assigned", []string{"NodeID-assigned"}, true, false, 0, 0, []string{"NodeID-assigned: node is assigned"}},
+		{"delete assigned with force", []string{"NodeID-assigned", "NodeID-retired"}, true, true, 2, 0, nil},
+		{"missing node", []string{"NodeID-missing"}, true, true, 0, 0, []string{"NodeID-missing: not found"}},
+	}
+	for _, c := range cases {
+		plan := planRemoval(c.nodeIDs, current, c.hard, c.force)
+		if len(plan.Rows) != c.wantRows || len(plan.Skipped) != c.wantSkipped || len(plan.Problems) != len(c.wantProblems) {
+			t.Errorf("%s: unexpected plan %+v", c.name, plan)
+			continue
+		}
+		for i, want := range c.wantProblems {
+			if !strings.HasPrefix(plan.Problems[i], want) {
+				t.Errorf("%s: expected problem %q, got %q", c.name, want, plan.Problems[i])
+			}
+		}
+	}
+}
+
+func TestRemoveNodes(t *testing.T) {
+	dev, srv, userID := startDevServer(t)
+	args := devUploadArgs(srv, writeNodesFile(t, 3))
+	session, err := getOrRequestAccessToken(&args.AuthArgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := uploadNodesToTable(args, session); err != nil {
+		t.Fatal(err)
+	}
+	client := newRestClient(session)
+	rows, err := client.fetchNodeRows(nil, 10, 0)
+	if err != nil || len(rows) != 3 {
+		t.Fatalf("expected 3 rows, got %d: %v", len(rows), err)
+	}
+
+	receiptDir := t.TempDir()
+	removeArgs := &NodesRemoveArgs{ReceiptDir: receiptDir, ChunkSize: 10, AuthArgs: args.AuthArgs}
+
+	// Anything but "yes" aborts without touching the backend or writing a receipt.
+	if _, err := removeNodes(client, removeArgs, rows[:2], strings.NewReader("y\n")); err != errRemovalAborted {
+		t.Fatalf("expected the removal to be aborted, got %v", err)
+	}
+	if got := dev.Requests("PATCH", nodesTablePath); got != 0 {
+		t.Fatalf("aborted removal sent %d updates", got)
+	}
+	if entries, _ := os.ReadDir(receiptDir); len(entries) != 0 {
+		t.Fatalf("aborted removal wrote %d receipts", len(entries))
+	}
+
+	removed, err := removeNodes(client, removeArgs, rows[:2], strings.NewReader("yes\n"))
+	if err != nil || removed != 2 {
+		t.Fatalf("expected 2 nodes retired, got %d: %v", removed, err)
+	}
+	retired := 0
+	for _, r := range dev.Rows() {
+		if r["node_state"] == nodeStateRetired {
+			retired++
+		}
+	}
+	if retired != 2 {
+		t.Fatalf("expected 2 retired rows, got %d", retired)
+	}
+
+	entries, err := os.ReadDir(receiptDir)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("expected one receipt, got %d: %v", len(entries), err)
+	}
+	data, err := os.ReadFile(filepath.Join(receiptDir, entries[0].Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var receipt removalReceipt
+	if err := json.Unmarshal(data, &receipt); err != nil {
+		t.Fatal(err)
+	}
+	if receipt.Mode != "retire" || receipt.UserID != userID || receipt.Profile != defaultProfileName || len(receipt.Nodes) != 2 {
+		t.Fatalf("unexpected receipt %+v", receipt)
+	}
+	// The receipt keeps the rows as they were before removal.
+	if receipt.Nodes[0].NodeID != rows[0].NodeID || receipt.Nodes[0].NodeState != rows[0].NodeState {
+		t.Fatalf("receipt row %+v does not match %+v", receipt.Nodes[0], rows[0])
+	}
+
+	// After a failed request, the receipt lists no removed rows and names the failed NodeIDs.
+	failedDir := t.TempDir()
+	removeArgs.ReceiptDir = failedDir
+	removeArgs.Yes = true
+	dev.InjectError("PATCH", nodesTablePath, 500, 1)
+	if _, err := removeNodes(client, removeArgs, rows[2:], nil); err == nil {
+		t.Fatal("expected the failed update to be reported")
+	}
+	entries, err = os.ReadDir(failedDir)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("expected one receipt, got %d: %v", len(entries), err)
+	}
+	data, err = os.ReadFile(filepath.Join(failedDir, entries[0].Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	receipt = removalReceipt{}
+	if err := json.Unmarshal(data, &receipt); err != nil {
+		t.Fatal(err)
+	}
+	if len(receipt.Nodes) != 0 || len(receipt.Failed) != 1 || receipt.Failed[0] != rows[2].NodeID {
+		t.Fatalf("unexpected receipt after a failure %+v", receipt)
+	}
+}
+
+func TestWriteRemovalReceiptDoesNotOverwrite(t *testing.T) {
+	dir := t.TempDir()
+	seen := map[string]bool{}
+	for i := 0; i < 3; i++ {
+		path, err := writeRemovalReceipt(dir, removalReceipt{Mode: "delete"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if seen[path] {
+			t.Fatalf("receipt %s was written twice", path)
+		}
+		seen[path] = true
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 3 {
+		t.Fatalf("expected 3 receipts, got %d", len(entries))
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/multisig-labs/tartarus/models"
+	"github.com/multisig-labs/tartarus/node"
+)
+
+// nodeSecretColumns is the PostgREST select list matching nodeSecretRow.
+const nodeSecretColumns = "node_id,bls_public_key,bls_signature,staker_cert,staker_key,bls_private_key"
+
+// nodeSecretRow is a row of the nodes table including the escrowed secret columns.
+type nodeSecretRow struct {
+	NodeID        string `json:"node_id"`
+	BLSPublicKey  string `json:"bls_public_key"`
+	BLSSignature  string `json:"bls_signature"`
+	StakerCert    string `json:"staker_cert"`
+	StakerKey     string `json:"staker_key"`
+	BLSPrivateKey string `json:"bls_private_key"`
+}
+
+// NodesFetchSecretsArgs defines the arguments for the 'nodes fetch-secrets' subcommand.
+type NodesFetchSecretsArgs struct {
+	Decrypt    bool     `cli:"--decrypt, Decrypt the secrets and write a nodes JSON file that convert can read"`
+	SecretsKey string   `cli:"--secrets-key, PEM private key (X25519 or RSA) matching the key the secrets were encrypted to"`
+	File       string   `cli:"-d, --data-file, File with NodeIDs to fetch: a nodes file, staking directories tree or one NodeID per line"`
+	Output     string   `cli:"-o, --output, Write the result to this file instead of stdout"`
+	ChunkSize  int      `cli:"--batch-size, Number of nodes to fetch per request" default:"100"`
+	NodeIDs    []string `cli:"node-ids, NodeIDs to fetch"`
+	AuthArgs
+}
+
+// fetchNodeSecrets fetches the secret columns for the given NodeIDs in chunks, keyed by NodeID.
+func (c *restClient) fetchNodeSecrets(nodeIDs []string, chunkSize int) (map[string]nodeSecretRow, error) {
+	found := map[string]nodeSecretRow{}
+	for i := 0; i < len(nodeIDs); i += chunkSize {
+		end := i + chunkSize
+		if end > len(nodeIDs) {
+			end = len(nodeIDs)
+		}
+		query := url.Values{}
+		query.Set("node_id", nodeIDInFilter(nodeIDs[i:end]))
+		query.Set("select", nodeSecretColumns)
+
+		status, respBody, err := c.do("GET", nodesTablePath, query, nil, "")
+		if err != nil {
+			return nil, err
+		}
+		if status != http.StatusOK {
+			return nil, fmt.Errorf("failed to fetch node secrets. Status: %d, Body: %s", status, string(respBody))
+		}
+
+		var rows []nodeSecretRow
+		if err := json.Unmarshal(respBody, &rows); err != nil {
+			return nil, fmt.Errorf("failed to parse nodes response JSON: %w", err)
+		}
+		for _, r := range rows {
+			found[r.NodeID] = r
+		}
+	}
+	return found, nil
+}
+
+// openNodeSecrets decrypts the secret columns of row and checks them against its public columns.
+// Values that were uploaded without a secrets recipient are passed through as they are.
+func openNodeSecrets(key *secretsKey, row nodeSecretRow) (models.Node, error) {
+	n := models.Node{
+		NodeID:       row.NodeID,
+		BLSPublicKey: normalizeHex(row.BLSPublicKey),
+		BLSSignature: normalizeHex(row.BLSSignature),
+	}
+	for _, field := range []struct {
+		column string
+		sealed string
+		value  *string
+	}{
+		{"staker_cert", row.StakerCert, &n.Cert},
+		{"staker_key", row.StakerKey, &n.Key},
+		{"bls_private_key", row.BLSPrivateKey, &n.BLSPrivateKey},
+	} {
+		if field.sealed == "" {
+			return models.Node{}, fmt.Errorf("%s is not escrowed", field.column)
+		}
+		if !isSealedSecret(field.sealed) {
+			fmt.Fprintf(os.Stderr, "Warning: %s of %s was stored in plaintext.\n", field.column, row.NodeID)
+			*field.value = field.sealed
+			continue
+		}
+		plaintext, err := key.Open(field.sealed, secretAAD(row.NodeID, field.column))
+		if err != nil {
+			return models.Node{}, fmt.Errorf("%s: %w", field.column, err)
+		}
+		*field.value = string(plaintext)
+	}
+
+	// Recovered keys are only useful if they belong to this node.
+	if problems := node.Validate(n); len(problems) > 0 {
+		return models.Node{}, fmt.Errorf("recovered secrets do not match the node: %v", problems[0])
+	}
+	return n, nil
+}
+
+// runNodesFetchSecretsCommand is the handler for the "nodes fetch-secrets" subcommand.
+// Without --decrypt it prints the escrowed values as stored; with --decrypt it recovers the keys.
+func runNodesFetchSecretsCommand() {
+	var fetchArgs NodesFetchSecretsArgs
+	parseAuthArgs("nodes fetch-secrets", &fetchArgs, &fetchArgs.AuthArgs)
+	if fetchArgs.ChunkSize <= 0 {
+		fetchArgs.ChunkSize = 100
+	}
+
+	nodeIDs, err := collectNodeIDs(fetchArgs.NodeIDs, fetchArgs.File)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if len(nodeIDs) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: no NodeIDs given. Pass them as arguments or with --data-file.")
+		os.Exit(1)
+	}
+
+	// Load the key before authenticating, so a wrong path fails fast.
+	var key *secretsKey
+	if fetchArgs.Decrypt {
+		if fetchArgs.SecretsKey == "" {
+			fmt.Fprintln(os.Stderr, "Error: --decrypt requires --secrets-key.")
+			os.Exit(1)
+		}
+		if key, err = loadSecretsKey(fetchArgs.SecretsKey); err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading secrets key: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	session, err := getOrRequestAccessToken(&fetchArgs.AuthArgs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error getting access token: %v\n", err)
+		os.Exit(1)
+	}
+
+	found, err := newRestClient(session).fetchNodeSecrets(nodeIDs, fetchArgs.ChunkSize)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error fetching node secrets: %v\n", err)
+		os.Exit(1)
+	}
+
+	var rows []nodeSecretRow
+	var nodes []models.Node
+	failed := 0
+	for _, id := range nodeIDs {
+		row, ok := found[id]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "%s: not found\n", id)
+			failed++
+			continue
+		}
+		rows = append(rows, row)
+		if key == nil {
+			continue
+		}
+		n, err := openNodeSecrets(key, row)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", id, err)
+			failed++
+			continue
+		}
+		nodes = append(nodes, n)
+	}
+	if failed > 0 {
+		fmt.Fprintf(os.Stderr, "Error: %d of %d nodes could not be recovered. Nothing was written.\n", failed, len(nodeIDs))
+		os.Exit(1)
+	}
+
+	var result interface{} = rows
+	if key != nil {
+		result = map[string][]models.Node{"nodes": nodes}
+	}
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error encoding JSON: %v\n", err)
+		os.Exit(1)
+	}
+
+	if fetchArgs.Output == "" {
+		fmt.Println(string(data))
+		return
+	}
+	// The file may hold private keys, so keep it readable by the owner only.
+	if err := os.WriteFile(fetchArgs.Output, data, 0600); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", fetchArgs.Output, err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "Wrote %d nodes to %s\n", len(rows), fetchArgs.Output)
+}
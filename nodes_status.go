@@ -0,0 +1,301 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Values of the hw_status column.
+const (
+	hwStatusInactive    = "inactive"
+	hwStatusActive      = "active"
+	hwStatusMaintenance = "maintenance"
+)
+
+// Values of the node_state column.
+const (
+	nodeStateAvailable = "available"
+	nodeStateAssigned  = "assigned"
+	nodeStateRetired   = "retired"
+)
+
+// hwStatusTransitions lists the hw_status values each status may move to.
+var hwStatusTransitions = map[string][]string{
+	hwStatusInactive:    {hwStatusActive, hwStatusMaintenance},
+	hwStatusActive:      {hwStatusInactive, hwStatusMaintenance},
+	hwStatusMaintenance: {hwStatusActive, hwStatusInactive},
+}
+
+// nodeStateTransitions lists the node_state values each state may move to.
+// An assigned node has to be released before it can be retired, and retired is final.
+var nodeStateTransitions = map[string][]string{
+	nodeStateAvailable: {nodeStateAssigned, nodeStateRetired},
+	nodeStateAssigned:  {nodeStateAvailable},
+	nodeStateRetired:   {},
+}
+
+// checkTransition returns an error if moving a column from one value to another is not allowed.
+// Setting a value to what it already is, is always allowed.
+func checkTransition(column string, transitions map[string][]string, from, to string) error {
+	if from == to {
+		return nil
+	}
+	if _, ok := transitions[to]; !ok {
+		return fmt.Errorf("unknown %s %q (expected one of: %s)", column, to, strings.Join(sortedKeys(transitions), ", "))
+	}
+	allowed, ok := transitions[from]
+	if !ok {
+		// Values we don't know about were set by someone else; let the server decide.
+		return nil
+	}
+	for _, a := range allowed {
+		if a == to {
+			return nil
+		}
+	}
+	return fmt.Errorf("cannot change %s from %q to %q", column, from, to)
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// readNodeIDList reads NodeIDs from a file with one NodeID per line, ignoring blank lines and
+// # comments. Anything else, such as a nodes file in any format upload accepts or a staking
+// directories tree, is read with readNodesFile.
+func readNodeIDList(path string) ([]string, error) {
+	if info, err := os.Stat(path); err == nil && !info.IsDir() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+
+		var nodeIDs []string
+		plainList := true
+		for _, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			if !strings.HasPrefix(line, "NodeID-") {
+				plainList = false
+				break
+			}
+			nodeIDs = append(nodeIDs, line)
+		}
+		if plainList {
+			return nodeIDs, nil
+		}
+	}
+
+	nodes, err := readNodesFile(path)
+	if err != nil {
+		return nil, err
+	}
+	nodeIDs := make([]string, len(nodes))
+	for i, n := range nodes {
+		nodeIDs[i] = n.NodeID
+	}
+	return nodeIDs, nil
+}
+
+// collectNodeIDs merges NodeIDs from the command line and an optional file, dropping duplicates.
+func collectNodeIDs(args []string, file string) ([]string, error) {
+	nodeIDs := append([]string{}, args...)
+	if file != "" {
+		fromFile, err := readNodeIDList(file)
+		if err != nil {
+			return nil, err
+		}
+		nodeIDs = append(nodeIDs, fromFile...)
+	}
+
+	seen := map[string]bool{}
+	var unique []string
+	for _, id := range nodeIDs {
+		if !strings.HasPrefix(id, "NodeID-") {
+			return nil, fmt.Errorf("invalid NodeID %q: must start with NodeID-", id)
+		}
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique, nil
+}
+
+// nodeIDInFilter builds a PostgREST in.() filter value for a list of NodeIDs.
+func nodeIDInFilter(nodeIDs []string) string {
+	return "in.(" + strings.Join(nodeIDs, ",") + ")"
+}
+
+// fetchNodeRowsByID fetches the rows for the given NodeIDs in chunks, keyed by NodeID.
+func (c *restClient) fetchNodeRowsByID(nodeIDs []string, chunkSize int) (map[string]NodeTableRow, error) {
+	found := map[string]NodeTableRow{}
+	for i := 0; i < len(nodeIDs); i += chunkSize {
+		end := i + chunkSize
+		if end > len(nodeIDs) {
+			end = len(nodeIDs)
+		}
+		filters := url.Values{}
+		filters.Set("node_id", nodeIDInFilter(nodeIDs[i:end]))
+		rows, err := c.fetchNodeRows(filters, chunkSize, 0)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range rows {
+			found[r.NodeID] = r
+		}
+	}
+	return found, nil
+}
+
+// patchNodeRows applies the same column update to every given NodeID, in chunks.
+// It returns the NodeIDs the server reports as updated.
+func (c *restClient) patchNodeRows(nodeIDs []string, update map[string]string, chunkSize int) ([]string, error) {
+	var updated []string
+	for i := 0; i < len(nodeIDs); i += chunkSize {
+		end := i + chunkSize
+		if end > len(nodeIDs) {
+			end = len(nodeIDs)
+		}
+		query := url.Values{}
+		query.Set("node_id", nodeIDInFilter(nodeIDs[i:end]))
+		query.Set("select", "node_id")
+
+		status, respBody, err := c.do("PATCH", nodesTablePath, query, update, "return=representation")
+		if err != nil {
+			return updated, err
+		}
+		if status != http.StatusOK {
+			return updated, fmt.Errorf("failed to update nodes. Status: %d, Body: %s", status, string(respBody))
+		}
+
+		var rows []struct {
+			NodeID string `json:"node_id"`
+		}
+		if err := json.Unmarshal(respBody, &rows); err != nil {
+			return updated, fmt.Errorf("failed to parse update response JSON: %w", err)
+		}
+		for _, r := range rows {
+			updated = append(updated, r.NodeID)
+		}
+	}
+	return updated, nil
+}
+
+// NodesSetStatusArgs defines the arguments for the 'nodes set-status' subcommand.
+type NodesSetStatusArgs struct {
+	HWStatus  string   `cli:"--hw-status, New hardware status (inactive, active or maintenance)"`
+	NodeState string   `cli:"--node-state, New node state (available, assigned or retired)"`
+	File      string   `cli:"-d, --data-file, File with NodeIDs to update: a nodes file, staking directories tree or one NodeID per line"`
+	ChunkSize int      `cli:"--batch-size, Number of nodes to update per request" default:"100"`
+	NodeIDs   []string `cli:"node-ids, NodeIDs to update"`
+	AuthArgs
+}
+
+// runNodesSetStatusCommand is the handler for the "nodes set-status" subcommand.
+func runNodesSetStatusCommand() {
+	var setArgs NodesSetStatusArgs
+	parseAuthArgs("nodes set-status", &setArgs, &setArgs.AuthArgs)
+
+	if setArgs.HWStatus == "" && setArgs.NodeState == "" {
+		fmt.Fprintln(os.Stderr, "Error: at least one of --hw-status or --node-state is required.")
+		os.Exit(1)
+	}
+	if _, ok := hwStatusTransitions[setArgs.HWStatus]; setArgs.HWStatus != "" && !ok {
+		fmt.Fprintf(os.Stderr, "Error: unknown hw_status %q (expected one of: %s)\n", setArgs.HWStatus, strings.Join(sortedKeys(hwStatusTransitions), ", "))
+		os.Exit(1)
+	}
+	if _, ok := nodeStateTransitions[setArgs.NodeState]; setArgs.NodeState != "" && !ok {
+		fmt.Fprintf(os.Stderr, "Error: unknown node_state %q (expected one of: %s)\n", setArgs.NodeState, strings.Join(sortedKeys(nodeStateTransitions), ", "))
+		os.Exit(1)
+	}
+	if setArgs.ChunkSize <= 0 {
+		setArgs.ChunkSize = 100
+	}
+
+	nodeIDs, err := collectNodeIDs(setArgs.NodeIDs, setArgs.File)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if len(nodeIDs) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: no NodeIDs given. Pass them as arguments or with --data-file.")
+		os.Exit(1)
+	}
+
+	session, err := getOrRequestAccessToken(&setArgs.AuthArgs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error getting access token: %v\n", err)
+		os.Exit(1)
+	}
+	client := newRestClient(session)
+
+	current, err := client.fetchNodeRowsByID(nodeIDs, setArgs.ChunkSize)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error fetching current node status: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Check every transition before changing anything, so a bad row doesn't leave a half-applied update.
+	var toUpdate []string
+	failed := 0
+	for _, id := range nodeIDs {
+		row, ok := current[id]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "%s: not found\n", id)
+			failed++
+			continue
+		}
+		if setArgs.HWStatus != "" {
+			if err := checkTransition("hw_status", hwStatusTransitions, row.HWStatus, setArgs.HWStatus); err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", id, err)
+				failed++
+				continue
+			}
+		}
+		if setArgs.NodeState != "" {
+			if err := checkTransition("node_state", nodeStateTransitions, row.NodeState, setArgs.NodeState); err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", id, err)
+				failed++
+				continue
+			}
+		}
+		toUpdate = append(toUpdate, id)
+	}
+	if failed > 0 {
+		fmt.Fprintf(os.Stderr, "Error: %d of %d nodes cannot be updated. Nothing was changed.\n", failed, len(nodeIDs))
+		os.Exit(1)
+	}
+
+	update := map[string]string{}
+	if setArgs.HWStatus != "" {
+		update["hw_status"] = setArgs.HWStatus
+	}
+	if setArgs.NodeState != "" {
+		update["node_state"] = setArgs.NodeState
+	}
+
+	updatedIDs, err := client.patchNodeRows(toUpdate, update, setArgs.ChunkSize)
+	updated := len(updatedIDs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error updating nodes (%d updated before the error): %v\n", updated, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Updated %d of %d nodes.\n", updated, len(toUpdate))
+	if updated != len(toUpdate) {
+		fmt.Fprintln(os.Stderr, "Warning: the server updated fewer nodes than requested. Check that you own them.")
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/multisig-labs/tartarus/models"
+)
+
+func TestCheckTransition(t *testing.T) {
+	cases := []struct {
+		column      string
+		transitions map[string][]string
+		from, to    string
+		wantErr     string // Empty if the transition is allowed
+	}{
+		// Allowed
+		{"hw_status", hwStatusTransitions, hwStatusInactive, hwStatusActive, ""},
+		{"hw_status", hwStatusTransitions, hwStatusActive, hwStatusMaintenance, ""},
+		{"hw_status", hwStatusTransitions, hwStatusMaintenance, hwStatusInactive, ""},
+		{"node_state", nodeStateTransitions, nodeStateAvailable, nodeStateAssigned, ""},
+		{"node_state", nodeStateTransitions, nodeStateAvailable, nodeStateRetired, ""},
+		{"node_state", nodeStateTransitions, nodeStateAssigned, nodeStateAvailable, ""},
+		{"node_state", nodeStateTransitions, nodeStateRetired, nodeStateRetired, ""}, // No change
+		// Rejected
+		{"node_state", nodeStateTransitions, nodeStateAssigned, nodeStateRetired, `cannot change node_state from "assigned" to "retired"`},
+		{"node_state", nodeStateTransitions, nodeStateRetired, nodeStateAvailable, `cannot change node_state from "retired" to "available"`},
+		{"node_state", nodeStateTransitions, nodeStateRetired, nodeStateAssigned, `cannot change node_state from "retired" to "assigned"`},
+		// Unknown states
+		{"hw_status", hwStatusTransitions, hwStatusActive, "broken", `unknown hw_status "broken" (expected one of: active, inactive, maintenance)`},
+		{"node_state", nodeStateTransitions, nodeStateAvailable, "", `unknown node_state ""`},
+		{"node_state", nodeStateTransitions, "quarantined", nodeStateAvailable, ""}, // Set outside tartarus, left to the server
+	}
+	for _, c := range cases {
+		err := checkTransition(c.column, c.transitions, c.from, c.to)
+		switch {
+		case c.wantErr == "" && err != nil:
+			t.Errorf("%s %q -> %q: unexpected error %v", c.column, c.from, c.to, err)
+		case c.wantErr != "" && (err == nil || !strings.HasPrefix(err.Error(), c.wantErr)):
+			t.Errorf("%s %q -> %q: expected error %q, got %v", c.column, c.from, c.to, c.wantErr, err)
+		}
+	}
+}
+
+func TestCollectNodeIDs(t *testing.T) {
+	dir := t.TempDir()
+	list := filepath.Join(dir, "ids.txt")
+	if err := os.WriteFile(list, []byte("# to retire\nNodeID-B\n\n  NodeID-C  \nNodeID-A\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := collectNodeIDs([]string{"NodeID-A", "NodeID-B", "NodeID-A"}, list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"NodeID-A", "NodeID-B", "NodeID-C"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+
+	if got, err := collectNodeIDs(nil, ""); err != nil || len(got) != 0 {
+		t.Fatalf("expected no NodeIDs, got %v, %v", got, err)
+	}
+	if _, err := collectNodeIDs([]string{"7Xhw2mDxuDS44j42TCB6U5579esbSt3Lg"}, ""); err == nil {
+		t.Fatal("expected a NodeID without the NodeID- prefix to be rejected")
+	}
+	if _, err := collectNodeIDs(nil, filepath.Join(dir, "missing.txt")); err == nil {
+		t.Fatal("expected an error for a missing file")
+	}
+}
+
+func TestReadNodeIDListFromNodesFile(t *testing.T) {
+	nodes := generateNodes(t, 2)
+	data, err := json.Marshal(map[string][]models.Node{"nodes": nodes})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "nodes.json")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := readNodeIDList(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{nodes[0].NodeID, nodes[1].NodeID}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// pagingNodesAPI serves total rows from the nodes table in PostgREST limit/offset pages,
+// and records the query of every request.
+func pagingNodesAPI(t *testing.T, total int) (*restClient, func() []url.Values) {
+	t.Helper()
+	var mu sync.Mutex
+	var queries []url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		queries = append(queries, r.URL.Query())
+		mu.Unlock()
+		if r.URL.Path != nodesTablePath {
+			http.NotFound(w, r)
+			return
+		}
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		page := []NodeTableRow{}
+		for i := offset; i < total && i < offset+limit; i++ {
+			page = append(page, NodeTableRow{NodeID: fmt.Sprintf("NodeID-%03d", i), Network: "fuji"})
+		}
+		json.NewEncoder(w).Encode(page)
+	}))
+	t.Cleanup(srv.Close)
+
+	session := &authSession{args: &AuthArgs{SupabaseURL: srv.URL}, AccessToken: "token"}
+	return newRestClient(session), func() []url.Values {
+		mu.Lock()
+		defer mu.Unlock()
+		return queries
+	}
+}
+
+func TestFetchNodeRowsPaginates(t *testing.T) {
+	cases := []struct {
+		name       string
+		total      int
+		pageSize   int
+		limit      int
+		wantRows   int
+		wantLimits []string
+	}{
+		{"several pages", 7, 3, 0, 7, []string{"3", "3", "3"}},
+		{"exact multiple ends on an empty page", 6, 3, 0, 6, []string{"3", "3", "3"}},
+		{"limit inside the last page", 7, 3, 5, 5, []string{"3", "2"}},
+		{"limit on a page boundary", 7, 3, 3, 3, []string{"3"}},
+		{"empty table", 0, 3, 0, 0, []string{"3"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			client, queries := pagingNodesAPI(t, c.total)
+			rows, err := client.fetchNodeRows(nil, c.pageSize, c.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rows) != c.wantRows {
+				t.Fatalf("expected %d rows, got %d", c.wantRows, len(rows))
+			}
+			for i, r := range rows {
+				if r.NodeID != fmt.Sprintf("NodeID-%03d", i) {
+					t.Fatalf("row %d is %s; pages were skipped or repeated", i, r.NodeID)
+				}
+			}
+			got := queries()
+			if len(got) != len(c.wantLimits) {
+				t.Fatalf("expected %d requests, got %d", len(c.wantLimits), len(got))
+			}
+			for i, q := range got {
+				if q.Get("limit") != c.wantLimits[i] || q.Get("offset") != strconv.Itoa(i*c.pageSize) {
+					t.Errorf("request %d: limit=%s offset=%s", i, q.Get("limit"), q.Get("offset"))
+				}
+				if q.Get("select") != nodeTableColumns || q.Get("order") != "node_id.asc" {
+					t.Errorf("request %d: unexpected select or order in %v", i, q)
+				}
+			}
+		})
+	}
+}
+
+func TestFetchNodeRowsFilters(t *testing.T) {
+	client, queries := pagingNodesAPI(t, 1)
+	filters := nodeRowFilters("fuji", "L1-abc", "active", "assigned", 7)
+	if _, err := client.fetchNodeRows(filters, 10, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"network":              "eq.fuji",
+		"l1_id":                "eq.L1-abc",
+		"hw_status":            "eq.active",
+		"node_state":           "eq.assigned",
+		"hardware_provider_id": "eq.7",
+	}
+	q := queries()[0]
+	for k, v := range want {
+		if q.Get(k) != v {
+			t.Errorf("%s: expected %q, got %q", k, v, q.Get(k))
+		}
+	}
+
+	if f := nodeRowFilters("", "", "", "", 0); len(f) != 0 {
+		t.Fatalf("expected no filters for empty values, got %v", f)
+	}
+}
+
+func TestFetchNodeRow(t *testing.T) {
+	client, queries := pagingNodesAPI(t, 1)
+	row, err := client.fetchNodeRow("NodeID-000")
+	if err != nil || row.NodeID != "NodeID-000" {
+		t.Fatalf("unexpected row %+v: %v", row, err)
+	}
+	if q := queries()[0]; q.Get("node_id") != "eq.NodeID-000" || q.Get("limit") != "1" {
+		t.Fatalf("unexpected query %v", q)
+	}
+
+	client, _ = pagingNodesAPI(t, 0)
+	if _, err := client.fetchNodeRow("NodeID-missing"); !errors.Is(err, errNodeNotFound) {
+		t.Fatalf("expected errNodeNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,410 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jxskiss/mcli"
+	"github.com/multisig-labs/tartarus/models"
+	"github.com/multisig-labs/tartarus/pchain"
+)
+
+// PChainAddValidatorArgs defines the arguments for the 'pchain add-validator' subcommand.
+type PChainAddValidatorArgs struct {
+	DataFile          string        `cli:"-d, --data-file, Nodes file (JSON, NDJSON or CSV) or a staking directories tree holding the node"`
+	Network           string        `cli:"--network, Network to build the transaction for (mainnet, fuji or network-<id>)" default:"mainnet"`
+	AVAXAssetID       string        `cli:"--avax-asset-id, AVAX asset ID, required for networks other than mainnet and fuji"`
+	Stake             string        `cli:"--stake, Amount of AVAX to stake (e.g. 2000)"`
+	Start             string        `cli:"--start, Start time (RFC 3339, defaults to now)"`
+	End               string        `cli:"--end, End time (RFC 3339)"`
+	Duration          time.Duration `cli:"--duration, Validation period, instead of --end (e.g. 336h)"`
+	Owner             string        `cli:"--owner, P-Chain address that owns the UTXOs and gets the stake and change back"`
+	RewardAddress     string        `cli:"--reward-address, P-Chain address for validation rewards (defaults to --owner)"`
+	DelegationAddress string        `cli:"--delegation-reward-address, P-Chain address for delegation fees (defaults to --reward-address)"`
+	DelegationFee     float64       `cli:"--delegation-fee, Delegation fee in percent" default:"2"`
+	Fee               string        `cli:"--fee, Transaction fee in AVAX to leave unspent" default:"0"`
+	UTXOs             []string      `cli:"--utxo, UTXO to spend as TXID:INDEX:AMOUNT, with the amount in AVAX (repeatable)"`
+	Format            string        `cli:"-f, --format, Encoding of the transaction: 0x or hex" default:"0x"`
+	Output            string        `cli:"-o, --output, Write the transaction to this file instead of stdout"`
+	NodeID            []string      `cli:"node-id, NodeID to register, if the data file holds more than one node"`
+}
+
+// selectNode returns the node with nodeID, or the only node if nodeID is empty.
+func selectNode(nodes []models.Node, nodeID string) (models.Node, error) {
+	if nodeID == "" {
+		if len(nodes) != 1 {
+			return models.Node{}, fmt.Errorf("the data file holds %d nodes; pass the NodeID to use", len(nodes))
+		}
+		return nodes[0], nil
+	}
+	for _, n := range nodes {
+		if n.NodeID == nodeID {
+			return n, nil
+		}
+	}
+	return models.Node{}, fmt.Errorf("%s is not in the data file", nodeID)
+}
+
+// validationPeriod resolves --start, --end and --duration.
+func validationPeriod(args *PChainAddValidatorArgs, now time.Time) (time.Time, time.Time, error) {
+	start := now
+	if args.Start != "" {
+		var err error
+		if start, err = time.Parse(time.RFC3339, args.Start); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid --start: %w", err)
+		}
+	}
+	switch {
+	case args.End != "" && args.Duration != 0:
+		return time.Time{}, time.Time{}, errors.New("pass --end or --duration, not both")
+	case args.End != "":
+		end, err := time.Parse(time.RFC3339, args.End)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid --end: %w", err)
+		}
+		return start, end, nil
+	case args.Duration > 0:
+		return start, start.Add(args.Duration), nil
+	default:
+		return time.Time{}, time.Time{}, errors.New("--end or --duration is required")
+	}
+}
+
+// addValidatorParams turns the command line into transaction parameters.
+func addValidatorParams(args *PChainAddValidatorArgs, n models.Node, now time.Time) (pchain.AddValidatorParams, error) {
+	p := pchain.AddValidatorParams{Node: n}
+	var err error
+	if p.Network, err = pchain.NetworkByName(args.Network, args.AVAXAssetID); err != nil {
+		return p, err
+	}
+	if p.Stake, err = pchain.ParseAVAX(args.Stake); err != nil {
+		return p, fmt.Errorf("invalid --stake: %w", err)
+	}
+	if p.Fee, err = pchain.ParseAVAX(args.Fee); err != nil {
+		return p, fmt.Errorf("invalid --fee: %w", err)
+	}
+	if p.Start, p.End, err = validationPeriod(args, now); err != nil {
+		return p, err
+	}
+	if p.DelegationShares, err = pchain.SharesFromPercent(args.DelegationFee); err != nil {
+		return p, err
+	}
+
+	rewardAddress, delegationAddress := args.RewardAddress, args.DelegationAddress
+	if rewardAddress == "" {
+		rewardAddress = args.Owner
+	}
+	if delegationAddress == "" {
+		delegationAddress = rewardAddress
+	}
+	if p.Owner, err = p.Network.ParseAddress(args.Owner); err != nil {
+		return p, fmt.Errorf("invalid --owner: %w", err)
+	}
+	if p.RewardAddress, err = p.Network.ParseAddress(rewardAddress); err != nil {
+		return p, fmt.Errorf("invalid --reward-address: %w", err)
+	}
+	if p.DelegationReward, err = p.Network.ParseAddress(delegationAddress); err != nil {
+		return p, fmt.Errorf("invalid --delegation-reward-address: %w", err)
+	}
+
+	for _, s := range args.UTXOs {
+		u, err := pchain.ParseUTXO(s)
+		if err != nil {
+			return p, err
+		}
+		p.UTXOs = append(p.UTXOs, u)
+	}
+	return p, nil
+}
+
+// runPChainAddValidatorCommand is the handler for the "pchain add-validator" subcommand.
+// It prints the unsigned transaction for the wallet that holds the UTXOs to sign and issue.
+func runPChainAddValidatorCommand() {
+	var addArgs PChainAddValidatorArgs
+	parseArgs("pchain add-validator", &addArgs)
+
+	switch {
+	case addArgs.DataFile == "":
+		fmt.Fprintln(os.Stderr, "Error: --data-file flag is required.")
+		mcli.PrintHelp()
+		os.Exit(1)
+	case addArgs.Stake == "" || addArgs.Owner == "" || len(addArgs.UTXOs) == 0:
+		fmt.Fprintln(os.Stderr, "Error: --stake, --owner and at least one --utxo are required.")
+		mcli.PrintHelp()
+		os.Exit(1)
+	case len(addArgs.NodeID) > 1:
+		fmt.Fprintln(os.Stderr, "Error: pass a single NodeID; each validator needs its own transaction.")
+		os.Exit(1)
+	}
+	checkKeyFormat(addArgs.Format)
+
+	nodes, err := readNodesFile(addArgs.DataFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	nodeID := ""
+	if len(addArgs.NodeID) == 1 {
+		nodeID = addArgs.NodeID[0]
+	}
+	n, err := selectNode(nodes, nodeID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	params, err := addValidatorParams(&addArgs, n, time.Now())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	_, unsigned, err := pchain.BuildAddValidatorTx(params)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error building the transaction: %v\n", err)
+		os.Exit(1)
+	}
+
+	out := encodeKeyHex(unsigned, addArgs.Format) + "\n"
+	if addArgs.Output != "" {
+		if err := os.WriteFile(addArgs.Output, []byte(out), 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", addArgs.Output, err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "Unsigned transaction saved to %s\n", addArgs.Output)
+	} else {
+		fmt.Print(out)
+	}
+	fmt.Fprintf(os.Stderr, "AddPermissionlessValidatorTx for %s: stake %s AVAX from %s to %s, fee %s AVAX. Sign it with the key of %s.\n",
+		n.NodeID, pchain.FormatAVAX(params.Stake),
+		params.Start.UTC().Format(time.RFC3339), params.End.UTC().Format(time.RFC3339),
+		pchain.FormatAVAX(params.Fee), addArgs.Owner)
+}
+
+// L1OwnerArgs defines the P-Chain owner flags shared by the L1 validator subcommands.
+type L1OwnerArgs struct {
+	Network                   string   `cli:"--network, Network the owner addresses are on (mainnet, fuji or network-<id>)" default:"mainnet"`
+	RemainingBalanceOwners    []string `cli:"--remaining-balance-owner, P-Chain address that gets the validator's remaining balance when it leaves (repeatable)"`
+	RemainingBalanceThreshold uint32   `cli:"--remaining-balance-threshold, Number of remaining balance owners that must sign" default:"1"`
+	DisableOwners             []string `cli:"--disable-owner, P-Chain address that may disable the validator (repeatable, defaults to the remaining balance owners)"`
+	DisableThreshold          uint32   `cli:"--disable-threshold, Number of disable owners that must sign" default:"1"`
+}
+
+// PChainL1ValidatorsArgs defines the arguments for the 'pchain l1-validators' subcommand.
+type PChainL1ValidatorsArgs struct {
+	DataFile string   `cli:"-d, --data-file, Nodes file (JSON, NDJSON or CSV) or a staking directories tree"`
+	Weight   uint64   `cli:"--weight, Consensus weight of each validator" default:"100"`
+	Balance  string   `cli:"--balance, AVAX each validator starts with to pay its continuous fee" default:"1"`
+	Output   string   `cli:"-o, --output, Write the entries to this file instead of stdout"`
+	NodeIDs  []string `cli:"node-ids, NodeIDs to include (defaults to every node in the data file)"`
+	L1OwnerArgs
+}
+
+// PChainRegisterL1ValidatorArgs defines the arguments for the 'pchain register-l1-validator' subcommand.
+type PChainRegisterL1ValidatorArgs struct {
+	DataFile      string        `cli:"-d, --data-file, Nodes file (JSON, NDJSON or CSV) or a staking directories tree"`
+	SubnetID      string        `cli:"--subnet-id, ID of the L1 (the subnet it was converted from)"`
+	Weight        uint64        `cli:"--weight, Consensus weight of each validator" default:"100"`
+	Expiry        string        `cli:"--expiry, Time after which the registration can no longer be used (RFC 3339)"`
+	ExpiresIn     time.Duration `cli:"--expires-in, Expiry relative to now, instead of --expiry (at most 24h)" default:"12h"`
+	SourceChainID string        `cli:"--source-chain-id, Chain of the validator manager; also wraps each message in an unsigned warp message"`
+	SourceAddress string        `cli:"--source-address, Hex address of the validator manager contract, with --source-chain-id"`
+	Output        string        `cli:"-o, --output, Write the messages to this file instead of stdout"`
+	NodeIDs       []string      `cli:"node-ids, NodeIDs to register (defaults to every node in the data file)"`
+	L1OwnerArgs
+}
+
+// l1Registration is the output of 'pchain register-l1-validator' for one node.
+type l1Registration struct {
+	NodeID              string `json:"node_id"`
+	ValidationID        string `json:"validation_id"`
+	Message             string `json:"message"`
+	UnsignedWarpMessage string `json:"unsigned_warp_message,omitempty"`
+}
+
+// l1Owners resolves the remaining balance and disable owners.
+func l1Owners(args *L1OwnerArgs) (pchain.Network, pchain.PChainOwner, pchain.PChainOwner, error) {
+	var remaining, disable pchain.PChainOwner
+	network, err := pchain.NetworkByName(args.Network, "")
+	if err != nil {
+		return network, remaining, disable, err
+	}
+	if len(args.RemainingBalanceOwners) == 0 {
+		return network, remaining, disable, errors.New("--remaining-balance-owner is required")
+	}
+	disableOwners := args.DisableOwners
+	if len(disableOwners) == 0 {
+		disableOwners = args.RemainingBalanceOwners
+	}
+	if remaining, err = network.ParseOwner(args.RemainingBalanceThreshold, args.RemainingBalanceOwners); err != nil {
+		return network, remaining, disable, fmt.Errorf("invalid --remaining-balance-owner: %w", err)
+	}
+	if disable, err = network.ParseOwner(args.DisableThreshold, disableOwners); err != nil {
+		return network, remaining, disable, fmt.Errorf("invalid --disable-owner: %w", err)
+	}
+	return network, remaining, disable, nil
+}
+
+// writeJSONOutput writes v as indented JSON to path, or to stdout if path is empty.
+func writeJSONOutput(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if path == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// runPChainL1ValidatorsCommand is the handler for the "pchain l1-validators" subcommand.
+// It prints the validators field of a ConvertSubnetToL1Tx, in avalanchego's JSON shape.
+func runPChainL1ValidatorsCommand() {
+	var l1Args PChainL1ValidatorsArgs
+	parseArgs("pchain l1-validators", &l1Args)
+	if l1Args.DataFile == "" {
+		fmt.Fprintln(os.Stderr, "Error: --data-file flag is required.")
+		mcli.PrintHelp()
+		os.Exit(1)
+	}
+
+	_, remaining, disable, err := l1Owners(&l1Args.L1OwnerArgs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	balance, err := pchain.ParseAVAX(l1Args.Balance)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid --balance: %v\n", err)
+		os.Exit(1)
+	}
+	nodes, err := readSelectedNodes(l1Args.DataFile, l1Args.NodeIDs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	validators := make([]pchain.L1Validator, 0, len(nodes))
+	for _, n := range nodes {
+		v, err := pchain.NewL1Validator(n, l1Args.Weight, balance, remaining, disable)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		validators = append(validators, v)
+	}
+	if err := pchain.SortL1Validators(validators); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := writeJSONOutput(l1Args.Output, validators); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing validators: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "Built %d ConvertSubnetToL1 validator entries with %s AVAX of balance each.\n", len(validators), pchain.FormatAVAX(balance))
+}
+
+// registrationExpiry resolves --expiry and --expires-in, enforcing the P-Chain's window.
+func registrationExpiry(args *PChainRegisterL1ValidatorArgs, now time.Time) (time.Time, error) {
+	expiry := now.Add(args.ExpiresIn)
+	if args.Expiry != "" {
+		var err error
+		if expiry, err = time.Parse(time.RFC3339, args.Expiry); err != nil {
+			return time.Time{}, fmt.Errorf("invalid --expiry: %w", err)
+		}
+	}
+	if !expiry.After(now) || expiry.Sub(now) > pchain.MaxRegistrationExpiry {
+		return time.Time{}, fmt.Errorf("expiry %s must be in the next %s", expiry.UTC().Format(time.RFC3339), pchain.MaxRegistrationExpiry)
+	}
+	return expiry, nil
+}
+
+// buildL1Registration builds the registration message of one node, and wraps it in a warp
+// message if source is set.
+func buildL1Registration(n models.Node, args *PChainRegisterL1ValidatorArgs, expiry time.Time, remaining, disable pchain.PChainOwner, source *pchain.WarpSource) (l1Registration, error) {
+	subnetID, err := pchain.ParseID(args.SubnetID)
+	if err != nil {
+		return l1Registration{}, fmt.Errorf("invalid --subnet-id: %w", err)
+	}
+	msg, err := pchain.NewRegisterL1ValidatorMessage(subnetID, n, expiry, args.Weight, remaining, disable)
+	if err != nil {
+		return l1Registration{}, err
+	}
+	b, err := msg.Bytes()
+	if err != nil {
+		return l1Registration{}, err
+	}
+	validationID, err := msg.ValidationID()
+	if err != nil {
+		return l1Registration{}, err
+	}
+	reg := l1Registration{NodeID: n.NodeID, ValidationID: validationID.String(), Message: encodeKeyHex(b, "0x")}
+	if source != nil {
+		unsigned, err := source.Wrap(b)
+		if err != nil {
+			return l1Registration{}, err
+		}
+		reg.UnsignedWarpMessage = encodeKeyHex(unsigned, "0x")
+	}
+	return reg, nil
+}
+
+// runPChainRegisterL1ValidatorCommand is the handler for the "pchain register-l1-validator" subcommand.
+// It prints the unsigned RegisterL1ValidatorMessage of each node, with the validation ID it will get.
+func runPChainRegisterL1ValidatorCommand() {
+	var regArgs PChainRegisterL1ValidatorArgs
+	parseArgs("pchain register-l1-validator", &regArgs)
+	if regArgs.DataFile == "" || regArgs.SubnetID == "" {
+		fmt.Fprintln(os.Stderr, "Error: --data-file and --subnet-id are required.")
+		mcli.PrintHelp()
+		os.Exit(1)
+	}
+
+	network, remaining, disable, err := l1Owners(&regArgs.L1OwnerArgs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	expiry, err := registrationExpiry(&regArgs, time.Now())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	var source *pchain.WarpSource
+	if regArgs.SourceChainID != "" {
+		source = &pchain.WarpSource{NetworkID: network.ID}
+		if source.ChainID, err = pchain.ParseID(regArgs.SourceChainID); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid --source-chain-id: %v\n", err)
+			os.Exit(1)
+		}
+		if source.Address, err = hex.DecodeString(normalizeHex(regArgs.SourceAddress)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid --source-address: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	nodes, err := readSelectedNodes(regArgs.DataFile, regArgs.NodeIDs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	registrations := make([]l1Registration, 0, len(nodes))
+	for _, n := range nodes {
+		reg, err := buildL1Registration(n, &regArgs, expiry, remaining, disable, source)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		registrations = append(registrations, reg)
+	}
+	if err := writeJSONOutput(regArgs.Output, registrations); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing messages: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "Built %d RegisterL1ValidatorMessages, valid until %s.\n", len(registrations), expiry.UTC().Format(time.RFC3339))
+}
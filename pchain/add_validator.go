@@ -0,0 +1,165 @@
+package pchain
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/snow"
+	"github.com/ava-labs/avalanchego/utils"
+	"github.com/ava-labs/avalanchego/utils/constants"
+	"github.com/ava-labs/avalanchego/utils/crypto/bls"
+	"github.com/ava-labs/avalanchego/vms/components/avax"
+	"github.com/ava-labs/avalanchego/vms/platformvm/reward"
+	"github.com/ava-labs/avalanchego/vms/platformvm/signer"
+	"github.com/ava-labs/avalanchego/vms/platformvm/txs"
+	"github.com/ava-labs/avalanchego/vms/secp256k1fx"
+	"github.com/multisig-labs/tartarus/models"
+)
+
+// AddValidatorParams describes a primary network validator to register.
+// Amounts are in nAVAX.
+type AddValidatorParams struct {
+	Network          Network
+	Node             models.Node // NodeID, BLSPublicKey and BLSSignature are used
+	Stake            uint64
+	Start, End       time.Time
+	RewardAddress    ids.ShortID // Receives validation rewards
+	DelegationReward ids.ShortID // Receives delegation fees
+	DelegationShares uint32      // Delegation fee in parts per million, e.g. 20000 for 2%
+	Owner            ids.ShortID // Owns the UTXOs, and gets the stake and change back
+	Fee              uint64
+	UTXOs            []UTXO
+}
+
+// SharesFromPercent converts a delegation fee in percent to the parts per million the P-Chain uses.
+func SharesFromPercent(percent float64) (uint32, error) {
+	if percent < 0 || percent > 100 {
+		return 0, fmt.Errorf("delegation fee %v%% is not between 0 and 100", percent)
+	}
+	return uint32(percent*reward.PercentDenominator/100 + 0.5), nil
+}
+
+// ProofOfPossession returns the BLS signer of a node record.
+func ProofOfPossession(n models.Node) (*signer.ProofOfPossession, error) {
+	pk, err := hex.DecodeString(strings.TrimPrefix(n.BLSPublicKey, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("BLS public key is not valid hex: %w", err)
+	}
+	sig, err := hex.DecodeString(strings.TrimPrefix(n.BLSSignature, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("BLS signature is not valid hex: %w", err)
+	}
+	if len(pk) != bls.PublicKeyLen || len(sig) != bls.SignatureLen {
+		return nil, fmt.Errorf("BLS public key and signature are %d and %d bytes, expected %d and %d", len(pk), len(sig), bls.PublicKeyLen, bls.SignatureLen)
+	}
+	pop := &signer.ProofOfPossession{}
+	copy(pop.PublicKey[:], pk)
+	copy(pop.ProofOfPossession[:], sig)
+	if err := pop.Verify(); err != nil {
+		return nil, fmt.Errorf("BLS proof of possession is invalid: %w", err)
+	}
+	return pop, nil
+}
+
+// owners returns single-signature output owners for addr.
+func owners(addr ids.ShortID) *secp256k1fx.OutputOwners {
+	return &secp256k1fx.OutputOwners{Threshold: 1, Addrs: []ids.ShortID{addr}}
+}
+
+// BuildAddValidatorTx builds an unsigned AddPermissionlessValidatorTx for the primary network.
+// The UTXOs are all spent: the stake is locked, the fee is burned and the rest is returned
+// to the owner as change. It returns the transaction and its unsigned bytes.
+func BuildAddValidatorTx(p AddValidatorParams) (*txs.AddPermissionlessValidatorTx, []byte, error) {
+	nodeID, err := ids.NodeIDFromString(p.Node.NodeID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid NodeID %q: %w", p.Node.NodeID, err)
+	}
+	pop, err := ProofOfPossession(p.Node)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", p.Node.NodeID, err)
+	}
+	switch {
+	case p.Network.AVAXAssetID == ids.Empty:
+		return nil, nil, fmt.Errorf("the AVAX asset ID of network %d is not known", p.Network.ID)
+	case p.Stake == 0:
+		return nil, nil, errors.New("stake must be greater than zero")
+	case !p.End.After(p.Start):
+		return nil, nil, errors.New("end time must be after start time")
+	case len(p.UTXOs) == 0:
+		return nil, nil, errors.New("no UTXOs to fund the stake and fee with")
+	}
+
+	var total uint64
+	ins := make([]*avax.TransferableInput, 0, len(p.UTXOs))
+	for _, u := range p.UTXOs {
+		if total+u.Amount < total {
+			return nil, nil, errors.New("UTXO amounts overflow")
+		}
+		total += u.Amount
+		ins = append(ins, &avax.TransferableInput{
+			UTXOID: avax.UTXOID{TxID: u.TxID, OutputIndex: u.OutputIndex},
+			Asset:  avax.Asset{ID: p.Network.AVAXAssetID},
+			In: &secp256k1fx.TransferInput{
+				Amt:   u.Amount,
+				Input: secp256k1fx.Input{SigIndices: []uint32{0}},
+			},
+		})
+	}
+	needed := p.Stake + p.Fee
+	if needed < p.Stake || total < needed {
+		return nil, nil, fmt.Errorf("UTXOs hold %s AVAX, but the stake and fee need %s AVAX", FormatAVAX(total), FormatAVAX(needed))
+	}
+	utils.Sort(ins)
+
+	var outs []*avax.TransferableOutput
+	if change := total - needed; change > 0 {
+		outs = append(outs, &avax.TransferableOutput{
+			Asset: avax.Asset{ID: p.Network.AVAXAssetID},
+			Out:   &secp256k1fx.TransferOutput{Amt: change, OutputOwners: *owners(p.Owner)},
+		})
+	}
+
+	tx := &txs.AddPermissionlessValidatorTx{
+		BaseTx: txs.BaseTx{BaseTx: avax.BaseTx{
+			NetworkID:    p.Network.ID,
+			BlockchainID: constants.PlatformChainID,
+			Ins:          ins,
+			Outs:         outs,
+		}},
+		Validator: txs.Validator{
+			NodeID: nodeID,
+			Start:  uint64(p.Start.Unix()),
+			End:    uint64(p.End.Unix()),
+			Wght:   p.Stake,
+		},
+		Subnet: constants.PrimaryNetworkID,
+		Signer: pop,
+		StakeOuts: []*avax.TransferableOutput{{
+			Asset: avax.Asset{ID: p.Network.AVAXAssetID},
+			Out:   &secp256k1fx.TransferOutput{Amt: p.Stake, OutputOwners: *owners(p.Owner)},
+		}},
+		ValidatorRewardsOwner: owners(p.RewardAddress),
+		DelegatorRewardsOwner: owners(p.DelegationReward),
+		DelegationShares:      p.DelegationShares,
+	}
+
+	ctx := &snow.Context{NetworkID: p.Network.ID, ChainID: constants.PlatformChainID, AVAXAssetID: p.Network.AVAXAssetID}
+	if err := tx.SyntacticVerify(ctx); err != nil {
+		return nil, nil, fmt.Errorf("built an invalid transaction: %w", err)
+	}
+	unsigned, err := MarshalUnsigned(tx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return tx, unsigned, nil
+}
+
+// MarshalUnsigned serializes an unsigned transaction the way it is signed: with the codec
+// version and type ID prefix, and without credentials.
+func MarshalUnsigned(tx txs.UnsignedTx) ([]byte, error) {
+	return txs.Codec.Marshal(txs.CodecVersion, &tx)
+}
@@ -0,0 +1,116 @@
+package pchain
+
+import (
+	"crypto/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/formatting/address"
+	"github.com/ava-labs/avalanchego/vms/platformvm/txs"
+	"github.com/ava-labs/avalanchego/vms/secp256k1fx"
+	"github.com/multisig-labs/tartarus/node"
+)
+
+func TestParseAVAX(t *testing.T) {
+	for s, want := range map[string]uint64{"2000": 2000 * NAVAXPerAVAX, "0.001": 1_000_000, "1.5": 1_500_000_000, ".000000001": 1} {
+		got, err := ParseAVAX(s)
+		if err != nil || got != want {
+			t.Errorf("ParseAVAX(%q) = %d, %v; want %d", s, got, err, want)
+		}
+		if back, _ := ParseAVAX(FormatAVAX(got)); back != got {
+			t.Errorf("FormatAVAX(%d) = %q does not round trip", got, FormatAVAX(got))
+		}
+	}
+	for _, s := range []string{"", "1.0000000001", "-1", "abc", "99999999999999999999"} {
+		if _, err := ParseAVAX(s); err == nil {
+			t.Errorf("ParseAVAX(%q) should fail", s)
+		}
+	}
+}
+
+func testAddress(t *testing.T, n Network) (ids.ShortID, string) {
+	t.Helper()
+	id := ids.GenerateTestShortID()
+	s, err := address.Format("P", n.HRP, id[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id, s
+}
+
+func TestBuildAddValidatorTx(t *testing.T) {
+	fuji, err := NetworkByName("fuji", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := node.GenerateFrom(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	owner, ownerAddr := testAddress(t, fuji)
+	if parsed, err := fuji.ParseAddress(ownerAddr); err != nil || parsed != owner {
+		t.Fatalf("ParseAddress(%s) = %s, %v", ownerAddr, parsed, err)
+	}
+	mainnet, _ := NetworkByName("mainnet", "")
+	if _, err := mainnet.ParseAddress(ownerAddr); err == nil {
+		t.Fatal("a fuji address was accepted on mainnet")
+	}
+	reward, _ := testAddress(t, fuji)
+
+	start := time.Unix(1_800_000_000, 0)
+	p := AddValidatorParams{
+		Network:          fuji,
+		Node:             n,
+		Stake:            1 * NAVAXPerAVAX,
+		Start:            start,
+		End:              start.Add(14 * 24 * time.Hour),
+		RewardAddress:    reward,
+		DelegationReward: reward,
+		DelegationShares: 20_000,
+		Owner:            owner,
+		Fee:              1_000_000,
+		UTXOs: []UTXO{
+			{TxID: ids.GenerateTestID(), OutputIndex: 1, Amount: NAVAXPerAVAX / 2},
+			{TxID: ids.GenerateTestID(), OutputIndex: 0, Amount: NAVAXPerAVAX},
+		},
+	}
+	_, unsigned, err := BuildAddValidatorTx(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var parsed txs.UnsignedTx
+	if _, err := txs.Codec.Unmarshal(unsigned, &parsed); err != nil {
+		t.Fatal(err)
+	}
+	tx, ok := parsed.(*txs.AddPermissionlessValidatorTx)
+	if !ok {
+		t.Fatalf("decoded a %T", parsed)
+	}
+	if tx.NodeID().String() != n.NodeID || tx.Wght != p.Stake || tx.Start != uint64(start.Unix()) || tx.DelegationShares != 20_000 {
+		t.Fatalf("unexpected validator %+v", tx.Validator)
+	}
+	pk, _, err := tx.PublicKey()
+	if err != nil || pk == nil {
+		t.Fatalf("BLS signer did not survive the round trip: %v", err)
+	}
+	if len(tx.Ins) != 2 || len(tx.Outs) != 1 {
+		t.Fatalf("expected 2 inputs and 1 change output, got %d and %d", len(tx.Ins), len(tx.Outs))
+	}
+	change := tx.Outs[0].Out.(*secp256k1fx.TransferOutput)
+	if change.Amt != NAVAXPerAVAX/2-p.Fee || change.Addrs[0] != owner {
+		t.Fatalf("unexpected change output %+v", change)
+	}
+
+	p.Stake = 2 * NAVAXPerAVAX
+	if _, _, err := BuildAddValidatorTx(p); err == nil || !strings.Contains(err.Error(), "need") {
+		t.Fatalf("expected an insufficient funds error, got %v", err)
+	}
+	p.Stake = NAVAXPerAVAX
+	p.Node.BLSSignature = n.BLSPublicKey + n.BLSPublicKey
+	if _, _, err := BuildAddValidatorTx(p); err == nil {
+		t.Fatal("expected an error for a bad proof of possession")
+	}
+}
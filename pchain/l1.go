@@ -0,0 +1,218 @@
+package pchain
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ava-labs/avalanchego/codec"
+	"github.com/ava-labs/avalanchego/codec/linearcodec"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/crypto/bls"
+	"github.com/ava-labs/avalanchego/vms/platformvm/signer"
+	"github.com/ava-labs/avalanchego/vms/platformvm/warp"
+	"github.com/ava-labs/avalanchego/vms/platformvm/warp/payload"
+	"github.com/ava-labs/avalanchego/vms/types"
+	"github.com/multisig-labs/tartarus/models"
+)
+
+// The L1 types below come from ACP-77 (avalanchego v1.12). The avalanchego version this module
+// builds against predates them, so they are mirrored here field for field. The serialize tags
+// and codec registration order must match upstream, or the bytes will not verify on the P-Chain.
+
+// PChainOwner is a set of P-Chain addresses and how many of them must sign.
+type PChainOwner struct {
+	Threshold uint32        `serialize:"true" json:"threshold"`
+	Addresses []ids.ShortID `serialize:"true" json:"addresses"`
+}
+
+// L1Validator is an entry of the validator set of a ConvertSubnetToL1Tx.
+type L1Validator struct {
+	NodeID                types.JSONByteSlice      `serialize:"true" json:"nodeID"`
+	Weight                uint64                   `serialize:"true" json:"weight"`
+	Balance               uint64                   `serialize:"true" json:"balance"`
+	Signer                signer.ProofOfPossession `serialize:"true" json:"signer"`
+	RemainingBalanceOwner PChainOwner              `serialize:"true" json:"remainingBalanceOwner"`
+	DeactivationOwner     PChainOwner              `serialize:"true" json:"deactivationOwner"`
+}
+
+// RegisterL1ValidatorMessage is the warp payload a validator manager sends to the P-Chain to
+// add a validator to an L1.
+type RegisterL1ValidatorMessage struct {
+	SubnetID              ids.ID                 `serialize:"true" json:"subnetID"`
+	NodeID                types.JSONByteSlice    `serialize:"true" json:"nodeID"`
+	BLSPublicKey          [bls.PublicKeyLen]byte `serialize:"true" json:"blsPublicKey"`
+	Expiry                uint64                 `serialize:"true" json:"expiry"`
+	RemainingBalanceOwner PChainOwner            `serialize:"true" json:"remainingBalanceOwner"`
+	DisableOwner          PChainOwner            `serialize:"true" json:"disableOwner"`
+	Weight                uint64                 `serialize:"true" json:"weight"`
+}
+
+// MaxRegistrationExpiry is how far in the future the P-Chain accepts a registration's expiry.
+const MaxRegistrationExpiry = 24 * time.Hour
+
+// messageCodec mirrors the codec of avalanchego's platformvm/warp/message package.
+var messageCodec = codec.NewDefaultManager()
+
+func init() {
+	lc := linearcodec.NewDefault()
+	lc.SkipRegistrations(1) // SubnetToL1Conversion
+	if err := errors.Join(
+		lc.RegisterType(&RegisterL1ValidatorMessage{}),
+		messageCodec.RegisterCodec(0, lc),
+	); err != nil {
+		panic(err)
+	}
+}
+
+// NewPChainOwner returns an owner requiring threshold of addrs. Addresses are sorted and
+// deduplicated, as the P-Chain requires.
+func NewPChainOwner(threshold uint32, addrs []ids.ShortID) (PChainOwner, error) {
+	sorted := append([]ids.ShortID(nil), addrs...)
+	sort.Slice(sorted, func(i, j int) bool { return bytes.Compare(sorted[i][:], sorted[j][:]) < 0 })
+	unique := sorted[:0]
+	for i, a := range sorted {
+		if i == 0 || a != sorted[i-1] {
+			unique = append(unique, a)
+		}
+	}
+	switch {
+	case len(unique) == 0 && threshold != 0:
+		return PChainOwner{}, errors.New("an owner with a threshold needs at least one address")
+	case int(threshold) > len(unique):
+		return PChainOwner{}, fmt.Errorf("threshold %d is more than the %d addresses", threshold, len(unique))
+	case len(unique) > 0 && threshold == 0:
+		return PChainOwner{}, errors.New("threshold must be at least 1")
+	}
+	return PChainOwner{Threshold: threshold, Addresses: unique}, nil
+}
+
+// ParseID parses a cb58 encoded ID, such as a subnet or chain ID.
+func ParseID(s string) (ids.ID, error) {
+	return ids.FromString(strings.TrimSpace(s))
+}
+
+// ParseOwner parses P-Chain addresses on the network into an owner requiring threshold of them.
+func (n Network) ParseOwner(threshold uint32, addrs []string) (PChainOwner, error) {
+	parsed := make([]ids.ShortID, 0, len(addrs))
+	for _, a := range addrs {
+		id, err := n.ParseAddress(a)
+		if err != nil {
+			return PChainOwner{}, err
+		}
+		parsed = append(parsed, id)
+	}
+	return NewPChainOwner(threshold, parsed)
+}
+
+// nodeIDBytes returns the raw 20 bytes of a NodeID, which is how L1 types encode it.
+func nodeIDBytes(nodeID string) ([]byte, error) {
+	id, err := ids.NodeIDFromString(nodeID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid NodeID %q: %w", nodeID, err)
+	}
+	return id.Bytes(), nil
+}
+
+// NewL1Validator returns the ConvertSubnetToL1Tx entry for a node. balance is in nAVAX and pays
+// the validator's continuous fee.
+func NewL1Validator(n models.Node, weight, balance uint64, remainingBalanceOwner, deactivationOwner PChainOwner) (L1Validator, error) {
+	nodeID, err := nodeIDBytes(n.NodeID)
+	if err != nil {
+		return L1Validator{}, err
+	}
+	if weight == 0 {
+		return L1Validator{}, errors.New("weight must be greater than zero")
+	}
+	pop, err := ProofOfPossession(n)
+	if err != nil {
+		return L1Validator{}, fmt.Errorf("%s: %w", n.NodeID, err)
+	}
+	return L1Validator{
+		NodeID:                nodeID,
+		Weight:                weight,
+		Balance:               balance,
+		Signer:                *pop,
+		RemainingBalanceOwner: remainingBalanceOwner,
+		DeactivationOwner:     deactivationOwner,
+	}, nil
+}
+
+// SortL1Validators sorts validators by NodeID bytes, the order ConvertSubnetToL1Tx requires,
+// and rejects a NodeID that appears more than once.
+func SortL1Validators(validators []L1Validator) error {
+	sort.Slice(validators, func(i, j int) bool { return bytes.Compare(validators[i].NodeID, validators[j].NodeID) < 0 })
+	for i := 1; i < len(validators); i++ {
+		if bytes.Equal(validators[i].NodeID, validators[i-1].NodeID) {
+			nodeID, _ := ids.ToNodeID(validators[i].NodeID)
+			return fmt.Errorf("%s is listed more than once", nodeID)
+		}
+	}
+	return nil
+}
+
+// NewRegisterL1ValidatorMessage returns the registration message for a node joining subnetID.
+func NewRegisterL1ValidatorMessage(subnetID ids.ID, n models.Node, expiry time.Time, weight uint64, remainingBalanceOwner, disableOwner PChainOwner) (*RegisterL1ValidatorMessage, error) {
+	nodeID, err := nodeIDBytes(n.NodeID)
+	if err != nil {
+		return nil, err
+	}
+	if weight == 0 {
+		return nil, errors.New("weight must be greater than zero")
+	}
+	// Registering does not need the proof of possession, but a bad key would make a useless validator.
+	pop, err := ProofOfPossession(n)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", n.NodeID, err)
+	}
+	return &RegisterL1ValidatorMessage{
+		SubnetID:              subnetID,
+		NodeID:                nodeID,
+		BLSPublicKey:          pop.PublicKey,
+		Expiry:                uint64(expiry.Unix()),
+		RemainingBalanceOwner: remainingBalanceOwner,
+		DisableOwner:          disableOwner,
+		Weight:                weight,
+	}, nil
+}
+
+// Bytes returns the serialized message, with the codec version and type ID prefix.
+func (m *RegisterL1ValidatorMessage) Bytes() ([]byte, error) {
+	var p interface{} = m
+	return messageCodec.Marshal(0, &p)
+}
+
+// ValidationID returns the ID the P-Chain gives the validator once the message is accepted:
+// the SHA-256 hash of the message bytes.
+func (m *RegisterL1ValidatorMessage) ValidationID() (ids.ID, error) {
+	b, err := m.Bytes()
+	if err != nil {
+		return ids.Empty, err
+	}
+	return sha256.Sum256(b), nil
+}
+
+// WarpSource is the validator manager contract that sends an L1's messages to the P-Chain.
+type WarpSource struct {
+	NetworkID uint32
+	ChainID   ids.ID // Chain the contract is deployed on
+	Address   []byte // Address of the contract
+}
+
+// Wrap wraps a message payload the way the validator manager sends it, ready for the L1's
+// validators to sign.
+func (s WarpSource) Wrap(message []byte) ([]byte, error) {
+	call, err := payload.NewAddressedCall(s.Address, message)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := warp.NewUnsignedMessage(s.NetworkID, s.ChainID, call.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return msg.Bytes(), nil
+}
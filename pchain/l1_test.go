@@ -0,0 +1,159 @@
+package pchain
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/vms/platformvm/warp"
+	"github.com/ava-labs/avalanchego/vms/platformvm/warp/payload"
+	"github.com/multisig-labs/tartarus/node"
+)
+
+func TestNewPChainOwner(t *testing.T) {
+	a, b := ids.ShortID{2}, ids.ShortID{1}
+	owner, err := NewPChainOwner(1, []ids.ShortID{a, b, a})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(owner.Addresses) != 2 || owner.Addresses[0] != b || owner.Addresses[1] != a {
+		t.Fatalf("addresses not sorted and deduplicated: %v", owner.Addresses)
+	}
+	if _, err := NewPChainOwner(3, []ids.ShortID{a, b}); err == nil {
+		t.Fatal("expected an error for a threshold above the number of addresses")
+	}
+	if _, err := NewPChainOwner(0, []ids.ShortID{a}); err == nil {
+		t.Fatal("expected an error for a zero threshold")
+	}
+}
+
+func TestRegisterL1ValidatorMessage(t *testing.T) {
+	n, err := node.GenerateFrom(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	owner, err := NewPChainOwner(1, []ids.ShortID{{7}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	subnetID := ids.GenerateTestID()
+	expiry := time.Unix(1_800_000_000, 0)
+	msg, err := NewRegisterL1ValidatorMessage(subnetID, n, expiry, 100, owner, owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := msg.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Build the expected encoding by hand from the ACP-77 layout.
+	nodeID, _ := ids.NodeIDFromString(n.NodeID)
+	pk, _ := hex.DecodeString(strings.TrimPrefix(n.BLSPublicKey, "0x"))
+	var want bytes.Buffer
+	put := func(v interface{}) { binary.Write(&want, binary.BigEndian, v) }
+	put(uint16(0)) // codec version
+	put(uint32(1)) // RegisterL1Validator type ID
+	want.Write(subnetID[:])
+	put(uint32(len(nodeID)))
+	want.Write(nodeID[:])
+	want.Write(pk)
+	put(uint64(expiry.Unix()))
+	for i := 0; i < 2; i++ { // remaining balance and disable owners
+		put(uint32(1))
+		put(uint32(1))
+		want.Write(owner.Addresses[0][:])
+	}
+	put(uint64(100))
+	if !bytes.Equal(b, want.Bytes()) {
+		t.Fatalf("unexpected encoding\n got %x\nwant %x", b, want.Bytes())
+	}
+
+	validationID, err := msg.ValidationID()
+	if err != nil || validationID != sha256.Sum256(b) {
+		t.Fatalf("validation ID %s is not the hash of the message: %v", validationID, err)
+	}
+
+	sourceChainID := ids.GenerateTestID()
+	unsigned, err := WarpSource{NetworkID: 5, ChainID: sourceChainID, Address: []byte{0xaa}}.Wrap(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := warp.ParseUnsignedMessage(unsigned)
+	if err != nil || parsed.NetworkID != 5 || parsed.SourceChainID != sourceChainID {
+		t.Fatalf("unexpected warp message %v: %v", parsed, err)
+	}
+	call, err := payload.ParseAddressedCall(parsed.Payload)
+	if err != nil || !bytes.Equal(call.Payload, b) {
+		t.Fatalf("warp payload does not carry the message: %v", err)
+	}
+}
+
+func TestNewL1ValidatorJSON(t *testing.T) {
+	n, err := node.GenerateFrom(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	owner, _ := NewPChainOwner(1, []ids.ShortID{{7}})
+	v, err := NewL1Validator(n, 100, NAVAXPerAVAX, owner, owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal([]L1Validator{v})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded []struct {
+		NodeID  string `json:"nodeID"`
+		Balance uint64 `json:"balance"`
+		Signer  struct {
+			PublicKey string `json:"publicKey"`
+		} `json:"signer"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	nodeID, _ := ids.NodeIDFromString(n.NodeID)
+	if decoded[0].NodeID != "0x"+hex.EncodeToString(nodeID[:]) || decoded[0].Balance != NAVAXPerAVAX {
+		t.Fatalf("unexpected JSON %s", data)
+	}
+	if !strings.EqualFold(strings.TrimPrefix(decoded[0].Signer.PublicKey, "0x"), strings.TrimPrefix(n.BLSPublicKey, "0x")) {
+		t.Fatalf("signer public key %s, expected %s", decoded[0].Signer.PublicKey, n.BLSPublicKey)
+	}
+}
+
+func TestSortL1Validators(t *testing.T) {
+	owner, _ := NewPChainOwner(0, nil)
+	var validators []L1Validator
+	for i := 0; i < 4; i++ {
+		n, err := node.GenerateFrom(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, err := NewL1Validator(n, 100, NAVAXPerAVAX, owner, owner)
+		if err != nil {
+			t.Fatal(err)
+		}
+		validators = append(validators, v)
+	}
+
+	if err := SortL1Validators(validators); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i < len(validators); i++ {
+		if bytes.Compare(validators[i-1].NodeID, validators[i].NodeID) >= 0 {
+			t.Fatalf("validators %d and %d are out of order", i-1, i)
+		}
+	}
+
+	if err := SortL1Validators(append(validators, validators[2])); err == nil || !strings.Contains(err.Error(), "more than once") {
+		t.Fatalf("expected a duplicate NodeID to be rejected, got %v", err)
+	}
+}
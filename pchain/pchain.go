@@ -0,0 +1,135 @@
+// Package pchain builds unsigned P-Chain transactions and L1 validator messages for generated
+// nodes. Everything is serialized with avalanchego's codec and left unsigned, so it can be
+// signed by whatever wallet or validator set is responsible for it.
+package pchain
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/constants"
+	"github.com/ava-labs/avalanchego/utils/formatting/address"
+)
+
+// NAVAXPerAVAX is the number of nAVAX, the smallest unit, in one AVAX.
+const NAVAXPerAVAX = 1_000_000_000
+
+// avaxAssetIDs are the AVAX asset IDs of the public networks.
+var avaxAssetIDs = map[uint32]string{
+	constants.MainnetID: "FvwEAhmxKfeiG8SnEvq42hc6whRyY3EFYAvebMqDNDGCgxN5Z",
+	constants.FujiID:    "U8iRqJoiJm8xZHAacmvYyZVwqQx6uDNtQeP3CQ6fcgQk3JqnK",
+}
+
+// Network is the network a transaction is built for.
+type Network struct {
+	ID          uint32
+	HRP         string
+	AVAXAssetID ids.ID
+}
+
+// NetworkByName returns the network called name (mainnet, fuji, or network-<id>).
+// assetID overrides the AVAX asset ID. It is only known for mainnet and fuji, and is left empty
+// for other networks unless given.
+func NetworkByName(name, assetID string) (Network, error) {
+	networkID, err := constants.NetworkID(name)
+	if err != nil {
+		return Network{}, err
+	}
+	n := Network{ID: networkID, HRP: constants.GetHRP(networkID)}
+	if assetID == "" {
+		assetID = avaxAssetIDs[networkID]
+	}
+	if assetID != "" {
+		if n.AVAXAssetID, err = ids.FromString(assetID); err != nil {
+			return Network{}, fmt.Errorf("invalid AVAX asset ID %q: %w", assetID, err)
+		}
+	}
+	return n, nil
+}
+
+// ParseAddress parses a P-Chain address such as P-avax1..., checking that it belongs to the network.
+// The P- prefix is optional.
+func (n Network) ParseAddress(s string) (ids.ShortID, error) {
+	s = strings.TrimSpace(s)
+	if !strings.Contains(s, "-") {
+		s = "P-" + s
+	}
+	chain, hrp, addr, err := address.Parse(s)
+	if err != nil {
+		return ids.ShortEmpty, fmt.Errorf("invalid address %q: %w", s, err)
+	}
+	if chain != "P" {
+		return ids.ShortEmpty, fmt.Errorf("%q is not a P-Chain address", s)
+	}
+	if hrp != n.HRP {
+		return ids.ShortEmpty, fmt.Errorf("%q is not an address on this network (expected the %s prefix)", s, n.HRP)
+	}
+	return ids.ToShortID(addr)
+}
+
+// ParseAVAX parses a decimal AVAX amount such as 2000 or 0.001 into nAVAX, without rounding.
+func ParseAVAX(s string) (uint64, error) {
+	s = strings.TrimSpace(s)
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" && frac == "" {
+		return 0, errors.New("empty amount")
+	}
+	if len(frac) > 9 {
+		return 0, fmt.Errorf("amount %q has more than 9 decimal places", s)
+	}
+	var w, f uint64
+	var err error
+	if whole != "" {
+		if w, err = strconv.ParseUint(whole, 10, 64); err != nil {
+			return 0, fmt.Errorf("invalid amount %q", s)
+		}
+	}
+	if frac != "" {
+		if f, err = strconv.ParseUint(frac+strings.Repeat("0", 9-len(frac)), 10, 64); err != nil {
+			return 0, fmt.Errorf("invalid amount %q", s)
+		}
+	}
+	if w > (math.MaxUint64-f)/NAVAXPerAVAX {
+		return 0, fmt.Errorf("amount %q is too large", s)
+	}
+	return w*NAVAXPerAVAX + f, nil
+}
+
+// FormatAVAX formats an amount of nAVAX as AVAX, without trailing zeros.
+func FormatAVAX(nAVAX uint64) string {
+	s := fmt.Sprintf("%d.%09d", nAVAX/NAVAXPerAVAX, nAVAX%NAVAXPerAVAX)
+	return strings.TrimSuffix(strings.TrimRight(s, "0"), ".")
+}
+
+// UTXO is an unspent AVAX output to fund a transaction with.
+// It must be owned by a single address, which signs with signature index 0.
+type UTXO struct {
+	TxID        ids.ID
+	OutputIndex uint32
+	Amount      uint64 // nAVAX
+}
+
+// ParseUTXO parses a UTXO given as <txID>:<output index>:<amount in AVAX>.
+func ParseUTXO(s string) (UTXO, error) {
+	parts := strings.Split(strings.TrimSpace(s), ":")
+	if len(parts) != 3 {
+		return UTXO{}, fmt.Errorf("invalid UTXO %q (expected TXID:INDEX:AMOUNT)", s)
+	}
+	txID, err := ids.FromString(parts[0])
+	if err != nil {
+		return UTXO{}, fmt.Errorf("invalid UTXO %q: bad transaction ID: %w", s, err)
+	}
+	index, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return UTXO{}, fmt.Errorf("invalid UTXO %q: bad output index: %w", s, err)
+	}
+	amount, err := ParseAVAX(parts[2])
+	if err != nil {
+		return UTXO{}, fmt.Errorf("invalid UTXO %q: %w", s, err)
+	}
+	return UTXO{TxID: txID, OutputIndex: uint32(index), Amount: amount}, nil
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// NodeTableRow is a row of the nodes table as returned by PostgREST.
+// Secret columns are deliberately left out so they are never selected by accident.
+type NodeTableRow struct {
+	NodeID             string `json:"node_id"`
+	BLSPublicKey       string `json:"bls_public_key"`
+	BLSSignature       string `json:"bls_signature"`
+	HardwareProviderID int    `json:"hardware_provider_id"`
+	UserID             string `json:"user_id"`
+	HWStatus           string `json:"hw_status"`
+	NodeState          string `json:"node_state"`
+	L1ID               string `json:"l1_id"`
+	Network            string `json:"network"`
+}
+
+// nodeTableColumns is the PostgREST select list matching NodeTableRow.
+const nodeTableColumns = "node_id,bls_public_key,bls_signature,hardware_provider_id,user_id,hw_status,node_state,l1_id,network"
+
+// errNodeNotFound is returned by fetchNodeRow when no row has the NodeID.
+var errNodeNotFound = errors.New("node not found")
+
+// restClient sends PostgREST requests on behalf of an authenticated session.
+type restClient struct {
+	session *authSession
+	client  *http.Client
+}
+
+func newRestClient(session *authSession) *restClient {
+	return &restClient{session: session, client: &http.Client{Timeout: time.Second * 30}}
+}
+
+// do sends a request to path (relative to the Supabase URL) and returns the status and body.
+// The token is refreshed beforehand if needed, and a 401 is retried once after renewing it.
+func (c *restClient) do(method, path string, query url.Values, body interface{}, prefer string) (int, []byte, error) {
+	var bodyBytes []byte
+	if body != nil {
+		var err error
+		bodyBytes, err = json.Marshal(body)
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to marshal request body: %w", err)
+		}
+	}
+
+	if err := c.session.EnsureFresh(); err != nil {
+		return 0, nil, fmt.Errorf("failed to refresh access token: %w", err)
+	}
+
+	status, respBody, err := c.send(method, path, query, bodyBytes, prefer)
+	if err != nil || status != http.StatusUnauthorized {
+		return status, respBody, err
+	}
+
+	if err := c.session.Renew(); err != nil {
+		return 0, nil, fmt.Errorf("failed to re-authenticate: %w", err)
+	}
+	return c.send(method, path, query, bodyBytes, prefer)
+}
+
+func (c *restClient) send(method, path string, query url.Values, bodyBytes []byte, prefer string) (int, []byte, error) {
+	reqURL := c.session.args.SupabaseURL + path
+	if len(query) > 0 {
+		reqURL += "?" + query.Encode()
+	}
+
+	var bodyReader io.Reader
+	if bodyBytes != nil {
+		bodyReader = bytes.NewReader(bodyBytes)
+	}
+	req, err := http.NewRequest(method, reqURL, bodyReader)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.session.AccessToken)
+	req.Header.Set("apikey", c.session.args.SupabaseAnonKey)
+	if prefer != "" {
+		req.Header.Set("Prefer", prefer)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, nil, fmt.Errorf("%s %s failed: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	return resp.StatusCode, respBody, nil
+}
+
+// fetchNodeRows pages through the nodes table with the given PostgREST filters.
+// limit caps the total number of rows returned; 0 means no cap.
+func (c *restClient) fetchNodeRows(filters url.Values, pageSize, limit int) ([]NodeTableRow, error) {
+	if pageSize <= 0 {
+		pageSize = 1000
+	}
+
+	var rows []NodeTableRow
+	for offset := 0; ; offset += pageSize {
+		n := pageSize
+		if limit > 0 && limit-len(rows) < n {
+			n = limit - len(rows)
+		}
+
+		query := url.Values{}
+		for k, v := range filters {
+			query[k] = v
+		}
+		query.Set("select", nodeTableColumns)
+		query.Set("order", "node_id.asc")
+		query.Set("limit", fmt.Sprint(n))
+		query.Set("offset", fmt.Sprint(offset))
+
+		status, respBody, err := c.do("GET", nodesTablePath, query, nil, "")
+		if err != nil {
+			return nil, err
+		}
+		if status != http.StatusOK {
+			return nil, fmt.Errorf("failed to list nodes. Status: %d, Body: %s", status, string(respBody))
+		}
+
+		var page []NodeTableRow
+		if err := json.Unmarshal(respBody, &page); err != nil {
+			return nil, fmt.Errorf("failed to parse nodes response JSON: %w", err)
+		}
+		rows = append(rows, page...)
+
+		if len(page) < n || (limit > 0 && len(rows) >= limit) {
+			return rows, nil
+		}
+	}
+}
+
+// fetchNodeRow returns the row of a single node, or errNodeNotFound.
+func (c *restClient) fetchNodeRow(nodeID string) (NodeTableRow, error) {
+	filters := url.Values{}
+	filters.Set("node_id", "eq."+nodeID)
+	rows, err := c.fetchNodeRows(filters, 1, 1)
+	if err != nil {
+		return NodeTableRow{}, err
+	}
+	if len(rows) == 0 {
+		return NodeTableRow{}, errNodeNotFound
+	}
+	return rows[0], nil
+}
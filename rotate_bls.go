@@ -0,0 +1,279 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jxskiss/mcli"
+	"github.com/multisig-labs/tartarus/models"
+	"github.com/multisig-labs/tartarus/node"
+)
+
+// RotateBLSArgs defines the arguments for the 'rotate-bls' subcommand.
+type RotateBLSArgs struct {
+	DataFile string   `cli:"-d, --data-file, Staking directory (or a tree of them) or nodes file (JSON, NDJSON or CSV) to rotate keys in"`
+	All      bool     `cli:"--all, Rotate the BLS key of every node in --data-file"`
+	Format   string   `cli:"-f, --format, Output format of the new keys: json or csv" default:"json"`
+	Output   string   `cli:"-o, --output, Write the new keys to this file instead of stdout"`
+	NodeIDs  []string `cli:"node-ids, NodeIDs to rotate the BLS key of"`
+}
+
+// blsRotation is the record of one rotated key, with what's needed to register it again.
+type blsRotation struct {
+	NodeID            string `json:"node_id"`
+	BLSPublicKey      string `json:"bls_public"`
+	BLSSignature      string `json:"bls_signature"`
+	PreviousBLSPublic string `json:"previous_bls_public"`
+	ArchivedTo        string `json:"archived_to"`
+}
+
+// archiveName returns the name an old key file is copied to before it is replaced.
+func archiveName(path string, now time.Time) string {
+	return fmt.Sprintf("%s.rotated-%s", path, now.UTC().Format("20060102T150405Z"))
+}
+
+// archiveFile copies path to archive, failing if archive already exists.
+func archiveFile(path, archive string, perm os.FileMode) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(archive, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// selectNodes returns the set of NodeIDs to rotate, checking that each of them is present.
+func selectNodes(nodes []models.Node, all bool, nodeIDs []string) (map[string]bool, error) {
+	present := map[string]bool{}
+	for _, n := range nodes {
+		present[n.NodeID] = true
+	}
+	if all {
+		return present, nil
+	}
+	selected := map[string]bool{}
+	for _, id := range nodeIDs {
+		if !present[id] {
+			return nil, fmt.Errorf("%s is not in the data file", id)
+		}
+		selected[id] = true
+	}
+	return selected, nil
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames it into place.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// rotateStakingDirs replaces signer.key in every selected staking directory under root.
+// The old key is kept under an archive name rather than deleted.
+func rotateStakingDirs(root string, all bool, nodeIDs []string, now time.Time) ([]blsRotation, error) {
+	dirs, err := node.StakingDirs(root)
+	if err != nil {
+		return nil, err
+	}
+	nodes := make([]models.Node, len(dirs))
+	for i, dir := range dirs {
+		if nodes[i], err = node.FromStakingDir(dir); err != nil {
+			return nil, err
+		}
+	}
+	selected, err := selectNodes(nodes, all, nodeIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var rotations []blsRotation
+	for i, dir := range dirs {
+		old := nodes[i]
+		if !selected[old.NodeID] {
+			continue
+		}
+		rotated, err := node.RotateBLS(old, rand.Reader)
+		if err != nil {
+			return rotations, fmt.Errorf("%s: %w", old.NodeID, err)
+		}
+		secret, err := hex.DecodeString(rotated.BLSPrivateKey)
+		if err != nil {
+			return rotations, fmt.Errorf("%s: %w", old.NodeID, err)
+		}
+
+		signerPath := filepath.Join(dir, node.SignerKeyFile)
+		info, err := os.Stat(signerPath)
+		if err != nil {
+			return rotations, err
+		}
+		archive := archiveName(signerPath, now)
+		if err := archiveFile(signerPath, archive, info.Mode().Perm()); err != nil {
+			return rotations, fmt.Errorf("failed to archive %s: %w", signerPath, err)
+		}
+		if err := writeFileAtomic(signerPath, secret, info.Mode().Perm()); err != nil {
+			return rotations, fmt.Errorf("failed to write %s (the old key is in %s): %w", signerPath, archive, err)
+		}
+
+		rotations = append(rotations, blsRotation{
+			NodeID:            old.NodeID,
+			BLSPublicKey:      rotated.BLSPublicKey,
+			BLSSignature:      rotated.BLSSignature,
+			PreviousBLSPublic: old.BLSPublicKey,
+			ArchivedTo:        archive,
+		})
+	}
+	return rotations, nil
+}
+
+// rotateNodesFile rewrites a nodes file in its own format with new BLS keys for the selected nodes.
+// The original file is kept under an archive name.
+func rotateNodesFile(path, format string, all bool, nodeIDs []string, now time.Time) ([]blsRotation, error) {
+	nodes, err := readNodesFile(path)
+	if err != nil {
+		return nil, err
+	}
+	selected, err := selectNodes(nodes, all, nodeIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	archive := archiveName(path, now)
+	var rotations []blsRotation
+	for i, old := range nodes {
+		if !selected[old.NodeID] {
+			continue
+		}
+		if nodes[i], err = node.RotateBLS(old, rand.Reader); err != nil {
+			return nil, fmt.Errorf("%s: %w", old.NodeID, err)
+		}
+		rotations = append(rotations, blsRotation{
+			NodeID:            old.NodeID,
+			BLSPublicKey:      nodes[i].BLSPublicKey,
+			BLSSignature:      nodes[i].BLSSignature,
+			PreviousBLSPublic: old.BLSPublicKey,
+			ArchivedTo:        archive,
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := node.WriteNodes(&buf, format, nodes); err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := archiveFile(path, archive, info.Mode().Perm()); err != nil {
+		return nil, fmt.Errorf("failed to archive %s: %w", path, err)
+	}
+	if err := writeFileAtomic(path, buf.Bytes(), info.Mode().Perm()); err != nil {
+		return nil, fmt.Errorf("failed to replace %s (the old file is in %s): %w", path, archive, err)
+	}
+	return rotations, nil
+}
+
+// writeRotations renders the new public keys and proofs of possession.
+func writeRotations(w io.Writer, rotations []blsRotation, format string) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(rotations)
+	case "csv":
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"node_id", "bls_public", "bls_signature", "previous_bls_public", "archived_to"})
+		for _, r := range rotations {
+			cw.Write([]string{r.NodeID, r.BLSPublicKey, r.BLSSignature, r.PreviousBLSPublic, r.ArchivedTo})
+		}
+		cw.Flush()
+		return cw.Error()
+	default:
+		return fmt.Errorf("unsupported format %q (expected json or csv)", format)
+	}
+}
+
+// runRotateBLSCommand is the handler for the "rotate-bls" subcommand.
+func runRotateBLSCommand() {
+	var rotateArgs RotateBLSArgs
+	parseArgs("rotate-bls", &rotateArgs)
+
+	if rotateArgs.DataFile == "" {
+		fmt.Fprintln(os.Stderr, "Error: --data-file flag is required.")
+		mcli.PrintHelp()
+		os.Exit(1)
+	}
+	if rotateArgs.All == (len(rotateArgs.NodeIDs) > 0) {
+		fmt.Fprintln(os.Stderr, "Error: pass the NodeIDs to rotate, or --all.")
+		os.Exit(1)
+	}
+	if rotateArgs.Format != "json" && rotateArgs.Format != "csv" {
+		fmt.Fprintf(os.Stderr, "Error: unsupported format %q (expected json or csv)\n", rotateArgs.Format)
+		os.Exit(1)
+	}
+
+	format, err := node.DetectFormat(rotateArgs.DataFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", rotateArgs.DataFile, err)
+		os.Exit(1)
+	}
+	now := time.Now()
+	var rotations []blsRotation
+	if format == node.FormatStakingDirs {
+		rotations, err = rotateStakingDirs(rotateArgs.DataFile, rotateArgs.All, rotateArgs.NodeIDs, now)
+	} else {
+		rotations, err = rotateNodesFile(rotateArgs.DataFile, format, rotateArgs.All, rotateArgs.NodeIDs, now)
+	}
+	if err != nil {
+		// Staking dirs are rotated one at a time, so report the ones that already changed.
+		for _, r := range rotations {
+			fmt.Fprintf(os.Stderr, "Rotated %s (old key in %s)\n", r.NodeID, r.ArchivedTo)
+		}
+		fmt.Fprintf(os.Stderr, "Error rotating BLS keys: %v\n", err)
+		os.Exit(1)
+	}
+
+	out := io.Writer(os.Stdout)
+	if rotateArgs.Output != "" {
+		f, err := os.Create(rotateArgs.Output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating %s: %v\n", rotateArgs.Output, err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		out = f
+	}
+	if err := writeRotations(out, rotations, rotateArgs.Format); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing new keys: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "Rotated the BLS key of %d nodes. Re-register the new bls_public and bls_signature before restarting them.\n", len(rotations))
+}
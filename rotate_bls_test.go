@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/multisig-labs/tartarus/models"
+	"github.com/multisig-labs/tartarus/node"
+)
+
+func generateNodes(t *testing.T, n int) []models.Node {
+	t.Helper()
+	nodes := make([]models.Node, n)
+	for i := range nodes {
+		var err error
+		if nodes[i], err = node.Generate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return nodes
+}
+
+func writeStakingDir(t *testing.T, dir string, n models.Node) {
+	t.Helper()
+	secret, err := hex.DecodeString(n.BLSPrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string][]byte{
+		node.StakerCertFile: []byte(n.Cert),
+		node.StakerKeyFile:  []byte(n.Key),
+		node.SignerKeyFile:  secret,
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRotateStakingDirs(t *testing.T) {
+	nodes := generateNodes(t, 2)
+	root := t.TempDir()
+	writeStakingDir(t, filepath.Join(root, "a"), nodes[0])
+	writeStakingDir(t, filepath.Join(root, "b"), nodes[1])
+	oldSigner, _ := os.ReadFile(filepath.Join(root, "a", node.SignerKeyFile))
+
+	if _, err := rotateStakingDirs(root, false, []string{"NodeID-unknown"}, time.Now()); err == nil {
+		t.Fatal("expected an error for a NodeID that isn't in the tree")
+	}
+
+	rotations, err := rotateStakingDirs(root, false, []string{nodes[0].NodeID}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rotations) != 1 || rotations[0].NodeID != nodes[0].NodeID || rotations[0].PreviousBLSPublic != nodes[0].BLSPublicKey {
+		t.Fatalf("unexpected rotations %+v", rotations)
+	}
+
+	rotated, err := node.FromStakingDir(filepath.Join(root, "a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rotated.NodeID != nodes[0].NodeID || rotated.Cert != nodes[0].Cert {
+		t.Fatal("the TLS identity changed")
+	}
+	if rotated.BLSPublicKey != rotations[0].BLSPublicKey || rotated.BLSSignature != rotations[0].BLSSignature {
+		t.Fatal("reported keys don't match signer.key")
+	}
+	if rotated.BLSPublicKey == nodes[0].BLSPublicKey {
+		t.Fatal("the BLS key was not replaced")
+	}
+	if problems := node.Validate(rotated); len(problems) > 0 {
+		t.Fatalf("rotated node is invalid: %v", problems)
+	}
+
+	archived, err := os.ReadFile(rotations[0].ArchivedTo)
+	if err != nil || !bytes.Equal(archived, oldSigner) {
+		t.Fatalf("old signer.key was not archived: %v", err)
+	}
+	untouched, err := node.FromStakingDir(filepath.Join(root, "b"))
+	if err != nil || untouched != nodes[1] {
+		t.Fatalf("unselected node changed: %v", err)
+	}
+}
+
+func TestRotateNodesFile(t *testing.T) {
+	nodes := generateNodes(t, 2)
+	path := filepath.Join(t.TempDir(), "nodes.ndjson")
+	var buf bytes.Buffer
+	if err := node.WriteNodes(&buf, node.FormatNDJSON, nodes); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	rotations, err := rotateNodesFile(path, node.FormatNDJSON, true, nil, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rotations) != 2 {
+		t.Fatalf("expected 2 rotations, got %d", len(rotations))
+	}
+
+	if format, _ := node.DetectFormat(path); format != node.FormatNDJSON {
+		t.Fatalf("file was rewritten as %s", format)
+	}
+	rotated, err := node.ReadNodes(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, n := range rotated {
+		if n.NodeID != nodes[i].NodeID || n.Key != nodes[i].Key {
+			t.Errorf("%s: the TLS identity changed", n.NodeID)
+		}
+		if n.BLSPublicKey != rotations[i].BLSPublicKey || n.BLSPublicKey == nodes[i].BLSPublicKey {
+			t.Errorf("%s: BLS key not rotated as reported", n.NodeID)
+		}
+		if problems := node.Validate(n); len(problems) > 0 {
+			t.Errorf("%s is invalid: %v", n.NodeID, problems)
+		}
+	}
+
+	archived, err := os.ReadFile(rotations[0].ArchivedTo)
+	if err != nil || !bytes.Equal(archived, buf.Bytes()) {
+		t.Fatalf("original file was not archived: %v", err)
+	}
+}
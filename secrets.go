@@ -0,0 +1,265 @@
+package main
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+)
+
+// Sealed secrets are stored as "tartarus:v1:<scheme>:<base64>", so the backend only ever sees
+// ciphertext and a reader can tell which key type is needed to open them.
+const (
+	sealedSecretPrefix = "tartarus:v1:"
+	sealSchemeX25519   = "x25519"
+	sealSchemeRSAOAEP  = "rsa-oaep"
+
+	// sealInfo is used as the HKDF info and the RSA-OAEP label, binding keys to this format.
+	sealInfo = "tartarus node secrets v1"
+)
+
+// secretsRecipient encrypts secrets to an operator's public key.
+type secretsRecipient struct {
+	x25519 *ecdh.PublicKey
+	rsa    *rsa.PublicKey
+}
+
+// secretsKey decrypts secrets with an operator's private key.
+type secretsKey struct {
+	x25519 *ecdh.PrivateKey
+	rsa    *rsa.PrivateKey
+}
+
+// readPEMBlock reads the first PEM block from path.
+func readPEMBlock(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found in %s", path)
+	}
+	return block, nil
+}
+
+// loadSecretsRecipient reads a PEM encoded X25519 or RSA public key, as written by
+// `openssl pkey -pubout`.
+func loadSecretsRecipient(path string) (*secretsRecipient, error) {
+	block, err := readPEMBlock(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var pub interface{}
+	switch block.Type {
+	case "PUBLIC KEY":
+		pub, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		pub, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%s: unexpected PEM block type %q, expected a public key", path, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key from %s: %w", path, err)
+	}
+
+	switch k := pub.(type) {
+	case *ecdh.PublicKey:
+		if k.Curve() != ecdh.X25519() {
+			return nil, fmt.Errorf("%s: only X25519 keys are supported for ECDH", path)
+		}
+		return &secretsRecipient{x25519: k}, nil
+	case *rsa.PublicKey:
+		if k.Size() < 256 {
+			return nil, fmt.Errorf("%s: RSA keys must be at least 2048 bits", path)
+		}
+		return &secretsRecipient{rsa: k}, nil
+	default:
+		return nil, fmt.Errorf("%s: unsupported public key type %T, use X25519 or RSA", path, pub)
+	}
+}
+
+// loadSecretsKey reads a PEM encoded X25519 or RSA private key, as written by `openssl genpkey`.
+func loadSecretsKey(path string) (*secretsKey, error) {
+	block, err := readPEMBlock(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var priv interface{}
+	switch block.Type {
+	case "PRIVATE KEY":
+		priv, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		priv, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%s: unexpected PEM block type %q, expected an unencrypted private key", path, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key from %s: %w", path, err)
+	}
+
+	switch k := priv.(type) {
+	case *ecdh.PrivateKey:
+		if k.Curve() != ecdh.X25519() {
+			return nil, fmt.Errorf("%s: only X25519 keys are supported for ECDH", path)
+		}
+		return &secretsKey{x25519: k}, nil
+	case *rsa.PrivateKey:
+		return &secretsKey{rsa: k}, nil
+	default:
+		return nil, fmt.Errorf("%s: unsupported private key type %T, use X25519 or RSA", path, priv)
+	}
+}
+
+// x25519DataKey derives the symmetric key for an X25519 sealed secret from the shared secret
+// and both public keys.
+func x25519DataKey(shared, ephemeralPub, recipientPub []byte) ([]byte, error) {
+	salt := append(append([]byte{}, ephemeralPub...), recipientPub...)
+	key := make([]byte, chacha20poly1305.KeySize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, shared, salt, []byte(sealInfo)), key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Seal encrypts plaintext to the recipient. aad is authenticated but not encrypted; callers pass
+// the NodeID and column name so a ciphertext cannot be moved to another row or column unnoticed.
+//
+// X25519: ephemeral public key (32) || nonce (24) || ciphertext, keyed with HKDF-SHA256 of the shared secret.
+// RSA-OAEP: wrapped data key (key size) || nonce (24) || ciphertext, the data key wrapped with OAEP-SHA256.
+// Both use XChaCha20-Poly1305 for the payload.
+func (r *secretsRecipient) Seal(plaintext, aad []byte) (string, error) {
+	var scheme string
+	var header, dataKey []byte
+
+	switch {
+	case r.x25519 != nil:
+		ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate ephemeral key: %w", err)
+		}
+		shared, err := ephemeral.ECDH(r.x25519)
+		if err != nil {
+			return "", fmt.Errorf("key agreement failed: %w", err)
+		}
+		header = ephemeral.PublicKey().Bytes()
+		if dataKey, err = x25519DataKey(shared, header, r.x25519.Bytes()); err != nil {
+			return "", fmt.Errorf("failed to derive data key: %w", err)
+		}
+		scheme = sealSchemeX25519
+	case r.rsa != nil:
+		dataKey = make([]byte, chacha20poly1305.KeySize)
+		if _, err := rand.Read(dataKey); err != nil {
+			return "", fmt.Errorf("failed to generate data key: %w", err)
+		}
+		var err error
+		if header, err = rsa.EncryptOAEP(sha256.New(), rand.Reader, r.rsa, dataKey, []byte(sealInfo)); err != nil {
+			return "", fmt.Errorf("failed to wrap data key: %w", err)
+		}
+		scheme = sealSchemeRSAOAEP
+	default:
+		return "", errors.New("no recipient key configured")
+	}
+
+	aead, err := chacha20poly1305.NewX(dataKey)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, chacha20poly1305.NonceSizeX)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sealed := append(header, nonce...)
+	sealed = aead.Seal(sealed, nonce, plaintext, aad)
+	return sealedSecretPrefix + scheme + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// isSealedSecret reports whether s was produced by Seal.
+func isSealedSecret(s string) bool {
+	return strings.HasPrefix(s, sealedSecretPrefix)
+}
+
+// Open decrypts a value produced by Seal with the same aad.
+func (k *secretsKey) Open(sealed string, aad []byte) ([]byte, error) {
+	if !isSealedSecret(sealed) {
+		return nil, errors.New("value is not a sealed secret")
+	}
+	scheme, encoded, ok := strings.Cut(strings.TrimPrefix(sealed, sealedSecretPrefix), ":")
+	if !ok {
+		return nil, errors.New("malformed sealed secret")
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("malformed sealed secret: %w", err)
+	}
+
+	var dataKey, rest []byte
+	switch scheme {
+	case sealSchemeX25519:
+		if k.x25519 == nil {
+			return nil, errors.New("secret was sealed to an X25519 key, but the given key is RSA")
+		}
+		const pubLen = 32
+		if len(data) < pubLen {
+			return nil, errors.New("sealed secret is too short")
+		}
+		ephemeral, err := ecdh.X25519().NewPublicKey(data[:pubLen])
+		if err != nil {
+			return nil, fmt.Errorf("invalid ephemeral key: %w", err)
+		}
+		shared, err := k.x25519.ECDH(ephemeral)
+		if err != nil {
+			return nil, fmt.Errorf("key agreement failed: %w", err)
+		}
+		if dataKey, err = x25519DataKey(shared, data[:pubLen], k.x25519.PublicKey().Bytes()); err != nil {
+			return nil, fmt.Errorf("failed to derive data key: %w", err)
+		}
+		rest = data[pubLen:]
+	case sealSchemeRSAOAEP:
+		if k.rsa == nil {
+			return nil, errors.New("secret was sealed to an RSA key, but the given key is X25519")
+		}
+		wrappedLen := k.rsa.Size()
+		if len(data) < wrappedLen {
+			return nil, errors.New("sealed secret is too short")
+		}
+		if dataKey, err = rsa.DecryptOAEP(sha256.New(), nil, k.rsa, data[:wrappedLen], []byte(sealInfo)); err != nil {
+			return nil, fmt.Errorf("failed to unwrap data key (wrong key?): %w", err)
+		}
+		rest = data[wrappedLen:]
+	default:
+		return nil, fmt.Errorf("unknown sealing scheme %q", scheme)
+	}
+
+	if len(rest) < chacha20poly1305.NonceSizeX {
+		return nil, errors.New("sealed secret is too short")
+	}
+	aead, err := chacha20poly1305.NewX(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, rest[:chacha20poly1305.NonceSizeX], rest[chacha20poly1305.NonceSizeX:], aad)
+	if err != nil {
+		return nil, errors.New("failed to decrypt secret (wrong key, or the value was tampered with)")
+	}
+	return plaintext, nil
+}
+
+// secretAAD binds a sealed value to its row and column.
+func secretAAD(nodeID, column string) []byte {
+	return []byte(nodeID + "/" + column)
+}
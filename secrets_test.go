@@ -0,0 +1,104 @@
+package main
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/multisig-labs/tartarus/models"
+	"github.com/multisig-labs/tartarus/node"
+)
+
+// writeKeyPair writes priv and its public key as PEM files and returns their paths.
+func writeKeyPair(t *testing.T, priv interface{}, pub interface{}) (string, string) {
+	t.Helper()
+	privDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	privPath := filepath.Join(dir, "operator.key")
+	pubPath := filepath.Join(dir, "operator.pub")
+	if err := os.WriteFile(privPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(pubPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return privPath, pubPath
+}
+
+func TestSealedSecretsRoundTrip(t *testing.T) {
+	x25519Key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := node.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, keys := range map[string][2]interface{}{
+		"x25519":   {x25519Key, x25519Key.PublicKey()},
+		"rsa-oaep": {rsaKey, &rsaKey.PublicKey},
+	} {
+		t.Run(name, func(t *testing.T) {
+			privPath, pubPath := writeKeyPair(t, keys[0], keys[1])
+
+			args := &UploadArgs{IncludeSecrets: true, SecretsRecipient: pubPath}
+			payloads, err := buildInsertPayloads(args, []models.Node{n}, "user")
+			if err != nil {
+				t.Fatal(err)
+			}
+			p := payloads[0]
+			for _, v := range []string{p.StakerCert, p.StakerKey, p.BLSPrivateKey} {
+				if !strings.HasPrefix(v, sealedSecretPrefix+name+":") {
+					t.Fatalf("secret is not sealed with %s: %.40s", name, v)
+				}
+			}
+			if strings.Contains(p.StakerKey, "PRIVATE KEY") {
+				t.Fatal("staker key leaked into the payload")
+			}
+
+			key, err := loadSecretsKey(privPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			row := nodeSecretRow{
+				NodeID:        p.NodeID,
+				BLSPublicKey:  p.BLSPublicKey,
+				BLSSignature:  p.BLSSignature,
+				StakerCert:    p.StakerCert,
+				StakerKey:     p.StakerKey,
+				BLSPrivateKey: p.BLSPrivateKey,
+			}
+			got, err := openNodeSecrets(key, row)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Cert != n.Cert || got.Key != n.Key || got.BLSPrivateKey != n.BLSPrivateKey {
+				t.Fatal("recovered secrets differ from the originals")
+			}
+
+			// A sealed value moved to another column must not open.
+			if _, err := key.Open(p.StakerKey, secretAAD(p.NodeID, "staker_cert")); err == nil {
+				t.Fatal("expected opening with the wrong column to fail")
+			}
+		})
+	}
+}
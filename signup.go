@@ -0,0 +1,332 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"syscall"
+	"unicode"
+
+	"golang.org/x/term"
+)
+
+const (
+	signupEndpointPath  = "/auth/v1/signup"
+	resendEndpointPath  = "/auth/v1/resend"
+	recoverEndpointPath = "/auth/v1/recover"
+	verifyEndpointPath  = "/auth/v1/verify"
+
+	minPasswordLength = 12
+)
+
+// ResetPasswordArgs defines the arguments for the 'auth reset-password' subcommand.
+type ResetPasswordArgs struct {
+	Token string `cli:"--token, One-time code from the password reset email; omit to request the email"`
+	AuthArgs
+}
+
+// checkPasswordStrength rejects passwords that are short, use too few character classes,
+// or contain the user's email name.
+func checkPasswordStrength(password, email string) error {
+	if len(password) < minPasswordLength {
+		return fmt.Errorf("password must be at least %d characters long", minPasswordLength)
+	}
+
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+	classes := 0
+	for _, present := range []bool{lower, upper, digit, symbol} {
+		if present {
+			classes++
+		}
+	}
+	if classes < 3 {
+		return errors.New("password must contain at least three of: lowercase letters, uppercase letters, digits, symbols")
+	}
+
+	if name, _, ok := strings.Cut(strings.ToLower(email), "@"); ok && len(name) >= 3 && strings.Contains(strings.ToLower(password), name) {
+		return errors.New("password must not contain your email address")
+	}
+	return nil
+}
+
+// promptForNewPassword asks for a password twice and checks that the entries match and are strong enough.
+func promptForNewPassword(email string) (string, error) {
+	if !stdinIsTerminal() {
+		return "", fmt.Errorf("cannot prompt for a new password: %w", errNoTerminal)
+	}
+	fmt.Println("WARNING: Password should be unique and not used anywhere else.")
+
+	fmt.Print("Enter your password: ")
+	password1, err := term.ReadPassword(int(syscall.Stdin))
+	fmt.Println() // Newline after password input
+	if err != nil {
+		return "", fmt.Errorf("failed to read password: %w", err)
+	}
+
+	fmt.Print("Confirm your password: ")
+	password2, err := term.ReadPassword(int(syscall.Stdin))
+	fmt.Println()
+	if err != nil {
+		return "", fmt.Errorf("failed to read password confirmation: %w", err)
+	}
+
+	if string(password1) != string(password2) {
+		return "", errors.New("passwords do not match, please try again")
+	}
+	if err := checkPasswordStrength(string(password1), email); err != nil {
+		return "", err
+	}
+	return string(password1), nil
+}
+
+// authRequest sends a JSON request to the Supabase auth API and returns the status code and body.
+// accessToken may be empty for endpoints that only need the anon key.
+func authRequest(args *AuthArgs, method, path, accessToken string, body interface{}) (int, []byte, error) {
+	requestBodyBytes, err := json.Marshal(body)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to marshal request body: %w", err)
+	}
+
+	req, err := http.NewRequest(method, args.SupabaseURL+path, bytes.NewBuffer(requestBodyBytes))
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("apikey", args.SupabaseAnonKey)
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+
+	client := &http.Client{Timeout: authRequestTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, nil, fmt.Errorf("request to %s failed: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	return resp.StatusCode, respBody, nil
+}
+
+// authErrorMessage extracts the human readable message from a Supabase auth error body.
+func authErrorMessage(respBody []byte) string {
+	var authErr struct {
+		Msg              string `json:"msg"`
+		Message          string `json:"message"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if json.Unmarshal(respBody, &authErr) == nil {
+		for _, m := range []string{authErr.Msg, authErr.Message, authErr.ErrorDescription} {
+			if m != "" {
+				return m
+			}
+		}
+	}
+	return string(respBody)
+}
+
+// emailOrPrompt returns the email from the flags, prompting for it if missing.
+func emailOrPrompt(args *AuthArgs) (string, error) {
+	if args.Email != "" {
+		return args.Email, nil
+	}
+	if args.PasswordStdin {
+		return "", errors.New("--password-stdin requires --email, since stdin can't be used for both")
+	}
+	email, err := promptForEmail()
+	if err != nil {
+		return "", err
+	}
+	if email == "" {
+		return "", errors.New("email is required")
+	}
+	args.Email = email
+	return email, nil
+}
+
+// runAuthSignupCommand is the handler for the "auth signup" subcommand.
+func runAuthSignupCommand() {
+	var signupArgs AuthArgs
+	parseAuthArgs("auth signup", &signupArgs, &signupArgs)
+
+	fmt.Println("GGP Node Manager User Signup")
+	fmt.Println("======================")
+
+	email, err := emailOrPrompt(&signupArgs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	password, err := suppliedPassword(&signupArgs)
+	if err == nil && password == "" {
+		password, err = promptForNewPassword(email)
+	} else if err == nil {
+		err = checkPasswordStrength(password, email)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	status, respBody, err := authRequest(&signupArgs, "POST", signupEndpointPath, "", map[string]string{"email": email, "password": password})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error signing up: %v\n", err)
+		os.Exit(1)
+	}
+	if status < 200 || status >= 300 {
+		fmt.Fprintf(os.Stderr, "Failed to sign up user. HTTP Status: %d: %s\n", status, authErrorMessage(respBody))
+		os.Exit(1)
+	}
+
+	// With email confirmation enabled Supabase returns just the user. If the project
+	// auto-confirms, it returns a full session, which we cache like a login.
+	var signupResponse struct {
+		authTokenResponse
+		ID                 string            `json:"id"`
+		ConfirmationSentAt string            `json:"confirmation_sent_at"`
+		Identities         []json.RawMessage `json:"identities"`
+	}
+	if err := json.Unmarshal(respBody, &signupResponse); err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing signup response: %v. Body: %s\n", err, string(respBody))
+		os.Exit(1)
+	}
+
+	if signupResponse.AccessToken != "" && signupResponse.User.ID != "" {
+		store, err := newCredentialStore(signupArgs.CredentialStore)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		session, err := newAuthSession(&signupArgs, store, &signupResponse.authTokenResponse)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		session.save()
+		session.rememberProfile(email)
+		fmt.Printf("User signed up and logged in as %s.\n", session.UserID)
+		return
+	}
+
+	// Supabase hides whether an address is already registered by returning a user with no identities.
+	if signupResponse.Identities != nil && len(signupResponse.Identities) == 0 {
+		fmt.Println("This email may already be registered. Try 'tartarus auth login', or 'tartarus auth reset-password' if you forgot your password.")
+		return
+	}
+
+	fmt.Println("User signed up successfully. Please check your email to verify your account.")
+	fmt.Println("You must verify your email address before you can log in. If the email does not arrive, run 'tartarus auth resend-verification'.")
+}
+
+// runAuthResendVerificationCommand is the handler for the "auth resend-verification" subcommand.
+func runAuthResendVerificationCommand() {
+	var resendArgs AuthArgs
+	parseAuthArgs("auth resend-verification", &resendArgs, &resendArgs)
+
+	email, err := emailOrPrompt(&resendArgs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	status, respBody, err := authRequest(&resendArgs, "POST", resendEndpointPath, "", map[string]string{"type": "signup", "email": email})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error resending verification email: %v\n", err)
+		os.Exit(1)
+	}
+	if status != http.StatusOK {
+		fmt.Fprintf(os.Stderr, "Failed to resend verification email. HTTP Status: %d: %s\n", status, authErrorMessage(respBody))
+		os.Exit(1)
+	}
+
+	fmt.Printf("If %s has a pending signup, a new verification email is on its way.\n", email)
+}
+
+// runAuthResetPasswordCommand is the handler for the "auth reset-password" subcommand.
+// Without --token it requests a reset email; with --token it verifies the code and sets a new password.
+func runAuthResetPasswordCommand() {
+	var resetArgs ResetPasswordArgs
+	parseAuthArgs("auth reset-password", &resetArgs, &resetArgs.AuthArgs)
+
+	email, err := emailOrPrompt(&resetArgs.AuthArgs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if resetArgs.Token == "" {
+		status, respBody, err := authRequest(&resetArgs.AuthArgs, "POST", recoverEndpointPath, "", map[string]string{"email": email})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error requesting password reset: %v\n", err)
+			os.Exit(1)
+		}
+		if status != http.StatusOK {
+			fmt.Fprintf(os.Stderr, "Failed to request password reset. HTTP Status: %d: %s\n", status, authErrorMessage(respBody))
+			os.Exit(1)
+		}
+		fmt.Printf("If %s is registered, a password reset email is on its way.\n", email)
+		fmt.Println("Run 'tartarus auth reset-password --token CODE' with the code from the email to choose a new password.")
+		return
+	}
+
+	// The code can only be used once, so settle on a new password before spending it.
+	password, err := suppliedPassword(&resetArgs.AuthArgs)
+	if err == nil && password == "" {
+		password, err = promptForNewPassword(email)
+	} else if err == nil {
+		err = checkPasswordStrength(password, email)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Exchange the one-time code for a short-lived session, then use it to set the new password.
+	status, respBody, err := authRequest(&resetArgs.AuthArgs, "POST", verifyEndpointPath, "", map[string]string{"type": "recovery", "email": email, "token": resetArgs.Token})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error verifying reset code: %v\n", err)
+		os.Exit(1)
+	}
+	if status != http.StatusOK {
+		fmt.Fprintf(os.Stderr, "Failed to verify reset code. HTTP Status: %d: %s\n", status, authErrorMessage(respBody))
+		os.Exit(1)
+	}
+	var verifyResponse authTokenResponse
+	if err := json.Unmarshal(respBody, &verifyResponse); err != nil || verifyResponse.AccessToken == "" {
+		fmt.Fprintf(os.Stderr, "Error: no session in verify response. Body: %s\n", string(respBody))
+		os.Exit(1)
+	}
+
+	status, respBody, err = authRequest(&resetArgs.AuthArgs, "PUT", userEndpointPath, verifyResponse.AccessToken, map[string]string{"password": password})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error setting new password: %v\n", err)
+		os.Exit(1)
+	}
+	if status != http.StatusOK {
+		fmt.Fprintf(os.Stderr, "Failed to set new password. HTTP Status: %d: %s\n", status, authErrorMessage(respBody))
+		os.Exit(1)
+	}
+
+	fmt.Println("Password updated. Run 'tartarus auth login' to log in with your new password.")
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/multisig-labs/tartarus/models"
+)
+
+// --- Sync Command Functionality ---
+
+// SyncDiffArgs defines the arguments for the 'sync diff' subcommand.
+type SyncDiffArgs struct {
+	DataFile           string `cli:"-d, --data-file, Nodes file (JSON, NDJSON or CSV) or a staking directories tree"`
+	Network            string `cli:"--network, Only compare against backend nodes on this network"`
+	L1ID               string `cli:"-L, --l1-id, Only compare against backend nodes assigned to this L1 ID"`
+	HardwareProviderID int    `cli:"--hp-id, Only compare against backend nodes of this Hardware Provider ID"`
+	JSON               bool   `cli:"--json, Print the differences as JSON"`
+	AuthArgs
+}
+
+// nodeMismatch describes a node present on both sides whose public BLS data differs.
+type nodeMismatch struct {
+	NodeID             string `json:"node_id"`
+	LocalBLSPublicKey  string `json:"local_bls_public_key,omitempty"`
+	RemoteBLSPublicKey string `json:"remote_bls_public_key,omitempty"`
+	LocalBLSSignature  string `json:"local_bls_signature,omitempty"`
+	RemoteBLSSignature string `json:"remote_bls_signature,omitempty"`
+	PublicKeyMismatch  bool   `json:"public_key_mismatch"`
+	SignatureMismatch  bool   `json:"signature_mismatch"`
+}
+
+// nodeDiff is the result of comparing local nodes against backend rows.
+type nodeDiff struct {
+	OnlyLocal  []string       `json:"only_local"`
+	OnlyRemote []string       `json:"only_remote"`
+	Mismatched []nodeMismatch `json:"mismatched"`
+	InSync     int            `json:"in_sync"`
+}
+
+// Empty reports whether both sides agree.
+func (d nodeDiff) Empty() bool {
+	return len(d.OnlyLocal) == 0 && len(d.OnlyRemote) == 0 && len(d.Mismatched) == 0
+}
+
+// normalizeHex lowercases a hex string and strips the 0x prefix so local and backend values compare equal.
+func normalizeHex(s string) string {
+	return strings.TrimPrefix(strings.ToLower(strings.TrimSpace(s)), "0x")
+}
+
+// diffNodes compares local nodes with backend rows by NodeID. Output order follows the inputs.
+func diffNodes(local []models.Node, remote []NodeTableRow) nodeDiff {
+	remoteByID := make(map[string]NodeTableRow, len(remote))
+	for _, r := range remote {
+		remoteByID[r.NodeID] = r
+	}
+
+	diff := nodeDiff{OnlyLocal: []string{}, OnlyRemote: []string{}, Mismatched: []nodeMismatch{}}
+	localIDs := make(map[string]bool, len(local))
+	for _, n := range local {
+		if localIDs[n.NodeID] {
+			continue
+		}
+		localIDs[n.NodeID] = true
+
+		r, ok := remoteByID[n.NodeID]
+		if !ok {
+			diff.OnlyLocal = append(diff.OnlyLocal, n.NodeID)
+			continue
+		}
+
+		m := nodeMismatch{
+			NodeID:            n.NodeID,
+			PublicKeyMismatch: normalizeHex(n.BLSPublicKey) != normalizeHex(r.BLSPublicKey),
+			SignatureMismatch: normalizeHex(n.BLSSignature) != normalizeHex(r.BLSSignature),
+		}
+		if !m.PublicKeyMismatch && !m.SignatureMismatch {
+			diff.InSync++
+			continue
+		}
+		if m.PublicKeyMismatch {
+			m.LocalBLSPublicKey, m.RemoteBLSPublicKey = with0x(normalizeHex(n.BLSPublicKey)), r.BLSPublicKey
+		}
+		if m.SignatureMismatch {
+			m.LocalBLSSignature, m.RemoteBLSSignature = with0x(normalizeHex(n.BLSSignature)), r.BLSSignature
+		}
+		diff.Mismatched = append(diff.Mismatched, m)
+	}
+
+	for _, r := range remote {
+		if !localIDs[r.NodeID] {
+			diff.OnlyRemote = append(diff.OnlyRemote, r.NodeID)
+		}
+	}
+	return diff
+}
+
+// printNodeDiff writes a human readable report of diff.
+func printNodeDiff(diff nodeDiff) {
+	fmt.Printf("In sync:          %d\n", diff.InSync)
+	fmt.Printf("Only local:       %d\n", len(diff.OnlyLocal))
+	for _, id := range diff.OnlyLocal {
+		fmt.Printf("  + %s\n", id)
+	}
+	fmt.Printf("Only remote:      %d\n", len(diff.OnlyRemote))
+	for _, id := range diff.OnlyRemote {
+		fmt.Printf("  - %s\n", id)
+	}
+	fmt.Printf("Mismatched:       %d\n", len(diff.Mismatched))
+	for _, m := range diff.Mismatched {
+		fmt.Printf("  ! %s\n", m.NodeID)
+		if m.PublicKeyMismatch {
+			fmt.Printf("      bls_public_key  local %s\n", m.LocalBLSPublicKey)
+			fmt.Printf("                      remote %s\n", m.RemoteBLSPublicKey)
+		}
+		if m.SignatureMismatch {
+			fmt.Printf("      bls_signature   local %s\n", m.LocalBLSSignature)
+			fmt.Printf("                      remote %s\n", m.RemoteBLSSignature)
+		}
+	}
+}
+
+// runSyncDiffCommand is the handler for the "sync diff" subcommand.
+// It exits with status 1 when local and backend disagree, so it can gate CI jobs.
+func runSyncDiffCommand() {
+	var diffArgs SyncDiffArgs
+	parseAuthArgs("sync diff", &diffArgs, &diffArgs.AuthArgs)
+
+	if diffArgs.DataFile == "" {
+		fmt.Fprintln(os.Stderr, "Error: --data-file flag is required.")
+		os.Exit(1)
+	}
+
+	local, err := readNodesFile(diffArgs.DataFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading local nodes: %v\n", err)
+		os.Exit(1)
+	}
+
+	session, err := getOrRequestAccessToken(&diffArgs.AuthArgs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error getting access token: %v\n", err)
+		os.Exit(1)
+	}
+
+	filters := nodeRowFilters(diffArgs.Network, diffArgs.L1ID, "", "", diffArgs.HardwareProviderID)
+	remote, err := newRestClient(session).fetchNodeRows(filters, 0, 0)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing backend nodes: %v\n", err)
+		os.Exit(1)
+	}
+
+	diff := diffNodes(local, remote)
+	if diffArgs.JSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(diff); err != nil {
+			fmt.Fprintf(os.Stderr, "Error encoding JSON: %v\n", err)
+			os.Exit(1)
+		}
+	} else {
+		fmt.Printf("Compared %d local nodes from %s with %d backend nodes.\n", len(local), diffArgs.DataFile, len(remote))
+		printNodeDiff(diff)
+	}
+
+	if !diff.Empty() {
+		os.Exit(1)
+	}
+}
+
+// runSyncPushCommand is the handler for the "sync push" subcommand.
+// It uploads only the local nodes the backend doesn't have yet.
+func runSyncPushCommand() {
+	var pushArgs UploadArgs
+	prof := parseAuthArgs("sync push", &pushArgs, &pushArgs.AuthArgs)
+	if pushArgs.HardwareProviderID == 0 {
+		pushArgs.HardwareProviderID = prof.HardwareProviderID
+	}
+	if pushArgs.SecretsRecipient == "" {
+		pushArgs.SecretsRecipient = prof.SecretsRecipient
+	}
+
+	if pushArgs.DataFile == "" {
+		fmt.Fprintln(os.Stderr, "Error: --data-file flag is required.")
+		os.Exit(1)
+	}
+	if pushArgs.HardwareProviderID == 0 {
+		fmt.Fprintln(os.Stderr, "Error: --hp-id (Hardware Provider ID) flag is required and must be non-zero.")
+		os.Exit(1)
+	}
+	if err := validateUploadArgs(&pushArgs); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v.\n", err)
+		os.Exit(1)
+	}
+
+	local, err := readNodesFile(pushArgs.DataFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading local nodes: %v\n", err)
+		os.Exit(1)
+	}
+	local, err = validateNodes(local, pushArgs.SkipInvalid)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	session, err := getOrRequestAccessToken(&pushArgs.AuthArgs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error getting access token: %v\n", err)
+		os.Exit(1)
+	}
+	client := newRestClient(session)
+
+	// Look up only the NodeIDs we have locally; the backend may hold many more.
+	localIDs := make([]string, len(local))
+	for i, n := range local {
+		localIDs[i] = n.NodeID
+	}
+	existing, err := client.fetchNodeRowsByID(localIDs, 100)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing backend nodes: %v\n", err)
+		os.Exit(1)
+	}
+
+	var missing []models.Node
+	for _, n := range local {
+		if _, ok := existing[n.NodeID]; !ok {
+			missing = append(missing, n)
+		}
+	}
+	fmt.Printf("%d of %d local nodes are already in the backend.\n", len(local)-len(missing), len(local))
+	if len(missing) == 0 {
+		fmt.Println("Nothing to push.")
+		return
+	}
+
+	if err := uploadNodes(&pushArgs, session, missing); err != nil {
+		fmt.Fprintf(os.Stderr, "Error uploading nodes: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("Push completed.")
+}
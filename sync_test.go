@@ -0,0 +1,35 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/multisig-labs/tartarus/models"
+)
+
+func TestDiffNodes(t *testing.T) {
+	local := []models.Node{
+		{NodeID: "NodeID-A", BLSPublicKey: "aa", BLSSignature: "bb"},
+		{NodeID: "NodeID-B", BLSPublicKey: "cc", BLSSignature: "dd"},
+		{NodeID: "NodeID-C", BLSPublicKey: "ee", BLSSignature: "ff"},
+	}
+	remote := []NodeTableRow{
+		{NodeID: "NodeID-A", BLSPublicKey: "0xAA", BLSSignature: "0xbb"},
+		{NodeID: "NodeID-B", BLSPublicKey: "0xcc", BLSSignature: "0x00"},
+		{NodeID: "NodeID-D", BLSPublicKey: "0x11", BLSSignature: "0x22"},
+	}
+
+	diff := diffNodes(local, remote)
+	if diff.InSync != 1 {
+		t.Errorf("expected 1 node in sync, got %d", diff.InSync)
+	}
+	if !reflect.DeepEqual(diff.OnlyLocal, []string{"NodeID-C"}) {
+		t.Errorf("unexpected only-local nodes: %v", diff.OnlyLocal)
+	}
+	if !reflect.DeepEqual(diff.OnlyRemote, []string{"NodeID-D"}) {
+		t.Errorf("unexpected only-remote nodes: %v", diff.OnlyRemote)
+	}
+	if len(diff.Mismatched) != 1 || diff.Mismatched[0].NodeID != "NodeID-B" || diff.Mismatched[0].PublicKeyMismatch || !diff.Mismatched[0].SignatureMismatch {
+		t.Errorf("unexpected mismatches: %+v", diff.Mismatched)
+	}
+}
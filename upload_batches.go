@@ -0,0 +1,203 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// maxRetryDelay caps the exponential backoff between attempts of one batch.
+const maxRetryDelay = 30 * time.Second
+
+// batchResult is the outcome of sending one batch, handed to the collector.
+type batchResult struct {
+	Num      int
+	Nodes    int
+	Code     int
+	Status   string
+	Body     []byte
+	Attempts int
+	Err      error
+	// Recovered is set when a retry conflicted after an attempt whose outcome was unknown, and the
+	// backend holds this batch's rows as this user: the earlier attempt was committed although
+	// its response was lost.
+	Recovered bool
+}
+
+// OK reports whether the batch was inserted. PostgREST answers 201 Created for a successful insert.
+func (r batchResult) OK() bool {
+	return r.Err == nil && (r.Code == http.StatusCreated || r.Recovered)
+}
+
+// sharedSession serializes token refreshes between concurrent batches, so a renewal triggered
+// by one batch is reused by the others instead of each logging in again.
+type sharedSession struct {
+	mu      sync.Mutex
+	session *authSession
+}
+
+// token returns a fresh access token.
+func (s *sharedSession) token() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.session.EnsureFresh(); err != nil {
+		return "", err
+	}
+	return s.session.AccessToken, nil
+}
+
+// renew replaces a rejected token. If another batch already replaced it, the new one is returned as is.
+func (s *sharedSession) renew(rejected string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.session.AccessToken == rejected {
+		if err := s.session.Renew(); err != nil {
+			return "", err
+		}
+	}
+	return s.session.AccessToken, nil
+}
+
+// retryableStatus reports whether a response status is worth retrying: rate limiting and server errors.
+func retryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code >= 500
+}
+
+// retryDelay returns how long to wait before the next attempt, honouring Retry-After in seconds.
+func retryDelay(attempt int, retryAfter string) time.Duration {
+	if secs, err := strconv.Atoi(retryAfter); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second
+	}
+	delay := time.Second << (attempt - 1)
+	if delay > maxRetryDelay || delay <= 0 {
+		delay = maxRetryDelay
+	}
+	return delay
+}
+
+// batchUploader sends batches to the nodes table. It is safe for concurrent use.
+type batchUploader struct {
+	client      *http.Client
+	url         string
+	anonKey     string
+	tokens      *sharedSession
+	rows        *restClient // Looks up the rows of a batch that conflicted after an uncertain attempt
+	limiter     *tokenBucket
+	maxAttempts int
+}
+
+// confirmStored checks that the backend holds every node of a batch as this batch would have
+// stored it: owned by the same user, with the same public data. That is the case when an earlier
+// attempt was committed, but not when the nodes were already uploaded before.
+func (u *batchUploader) confirmStored(payloadBytes []byte) error {
+	var payloads []NodeTableInsertPayload
+	if err := json.Unmarshal(payloadBytes, &payloads); err != nil {
+		return err
+	}
+	nodeIDs := make([]string, len(payloads))
+	for i, p := range payloads {
+		nodeIDs[i] = p.NodeID
+	}
+
+	// The lookup shares the session with the batches, so keep their token refreshes out of its way.
+	u.tokens.mu.Lock()
+	rows, err := u.rows.fetchNodeRowsByID(nodeIDs, 100)
+	u.tokens.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to look up the conflicting nodes: %w", err)
+	}
+
+	for _, p := range payloads {
+		r, ok := rows[p.NodeID]
+		switch {
+		case !ok:
+			return fmt.Errorf("%s is not in the backend", p.NodeID)
+		case r.UserID != p.UserID:
+			return fmt.Errorf("%s was uploaded by another user", p.NodeID)
+		case normalizeHex(r.BLSPublicKey) != normalizeHex(p.BLSPublicKey) || normalizeHex(r.BLSSignature) != normalizeHex(p.BLSSignature),
+			r.HardwareProviderID != p.HardwareProviderID, r.Network != p.Network, r.L1ID != p.L1ID:
+			return fmt.Errorf("%s is already in the backend with different data", p.NodeID)
+		}
+	}
+	return nil
+}
+
+// send POSTs one batch, retrying network errors, 429 and 5xx responses up to maxAttempts times.
+// A 401 is retried once after renewing the token, without counting against the attempts.
+// An insert is not idempotent, so a 409 after a network error or 5xx may mean the earlier attempt
+// went through. It counts as success if the backend holds the batch's rows as this upload would have.
+func (u *batchUploader) send(num, nodes int, payloadBytes []byte) batchResult {
+	res := batchResult{Num: num, Nodes: nodes}
+
+	renewed, uncertain := false, false
+	for attempt := 1; ; {
+		// Long uploads and backoffs can outlive the access token, so check it before every attempt.
+		token, err := u.tokens.token()
+		if err != nil {
+			res.Err = fmt.Errorf("failed to refresh access token: %w", err)
+			return res
+		}
+
+		res.Attempts++
+		u.limiter.Wait()
+
+		var delay time.Duration
+		resp, err := postNodesBatch(u.client, u.url, u.anonKey, token, payloadBytes)
+		if err != nil {
+			res.Err = fmt.Errorf("failed to send batch: %w", err)
+			delay = retryDelay(attempt, "")
+			uncertain = true
+		} else {
+			body, ioErr := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			res.Code, res.Status, res.Body, res.Err = resp.StatusCode, resp.Status, body, nil
+			if ioErr != nil {
+				res.Err = fmt.Errorf("failed to read response body: %w", ioErr)
+			}
+
+			// The token can still be revoked or expire server-side mid-batch. Renew and retry once.
+			if resp.StatusCode == http.StatusUnauthorized && !renewed {
+				renewed = true
+				fmt.Fprintf(os.Stderr, "Batch %d was rejected with %s, re-authenticating and retrying...\n", num, resp.Status)
+				if _, err := u.tokens.renew(token); err != nil {
+					res.Err = fmt.Errorf("failed to re-authenticate: %w", err)
+					return res
+				}
+				continue
+			}
+			if resp.StatusCode == http.StatusConflict && uncertain {
+				if err := u.confirmStored(payloadBytes); err != nil {
+					fmt.Fprintf(os.Stderr, "Batch %d conflicts after an attempt that may have failed, and is not stored as sent: %v\n", num, err)
+					return res
+				}
+				fmt.Fprintf(os.Stderr, "Batch %d conflicts with its own earlier attempt, which was stored although it looked failed.\n", num)
+				res.Recovered = true
+				return res
+			}
+			if !retryableStatus(resp.StatusCode) {
+				return res
+			}
+			// A 429 was turned away before the insert; a 5xx may have failed after it.
+			if resp.StatusCode >= 500 {
+				uncertain = true
+			}
+			delay = retryDelay(attempt, resp.Header.Get("Retry-After"))
+		}
+
+		if attempt >= u.maxAttempts {
+			return res
+		}
+		reason := res.Status
+		if res.Err != nil {
+			reason = res.Err.Error()
+		}
+		fmt.Fprintf(os.Stderr, "Batch %d attempt %d failed (%s), retrying in %s...\n", num, attempt, reason, delay)
+		time.Sleep(delay)
+		attempt++
+	}
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/multisig-labs/tartarus/models"
+)
+
+func TestUploadNodesConcurrentRetries(t *testing.T) {
+	// Response files are written to the working directory.
+	wd, _ := os.Getwd()
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	var mu sync.Mutex
+	attempts := map[string]int{} // first NodeID of the batch -> attempts
+	inserted := map[string]int{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var batch []NodeTableInsertPayload
+		if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		first := batch[0].NodeID
+		attempts[first]++
+		switch {
+		case first == "NodeID-4":
+			// A conflict is permanent and must not be retried.
+			w.WriteHeader(http.StatusConflict)
+			return
+		case attempts[first] == 1:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		for _, p := range batch {
+			inserted[p.NodeID]++
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(batch)
+	}))
+	defer srv.Close()
+
+	var nodes []models.Node
+	for i := 0; i < 10; i++ {
+		nodes = append(nodes, models.Node{NodeID: fmt.Sprintf("NodeID-%d", i), BLSPublicKey: "aa", BLSSignature: "bb"})
+	}
+	args := &UploadArgs{
+		HardwareProviderID: 1,
+		Network:            "fuji",
+		BatchSize:          2,
+		Concurrency:        3,
+		Retries:            2,
+		RequestTimeout:     5 * time.Second,
+		AuthArgs:           AuthArgs{SupabaseURL: srv.URL},
+	}
+	session := &authSession{args: &args.AuthArgs, AccessToken: "token", UserID: "user"}
+
+	err := uploadNodes(args, session, nodes)
+	if err == nil || err.Error() != "1 of 5 batches failed" {
+		t.Fatalf("expected exactly the conflicting batch to fail, got %v", err)
+	}
+
+	if attempts["NodeID-4"] != 1 {
+		t.Errorf("conflicting batch was sent %d times, expected 1", attempts["NodeID-4"])
+	}
+	for _, first := range []string{"NodeID-0", "NodeID-2", "NodeID-6", "NodeID-8"} {
+		if attempts[first] != 2 {
+			t.Errorf("batch starting at %s was sent %d times, expected 2", first, attempts[first])
+		}
+	}
+	for _, n := range nodes {
+		want := 1
+		if n.NodeID == "NodeID-4" || n.NodeID == "NodeID-5" {
+			want = 0
+		}
+		if inserted[n.NodeID] != want {
+			t.Errorf("%s inserted %d times, expected %d", n.NodeID, inserted[n.NodeID], want)
+		}
+	}
+	for i := 1; i <= 5; i++ {
+		if _, err := os.Stat(fmt.Sprintf("upload_nodes_response_batch_%d.json", i)); err != nil {
+			t.Errorf("missing response file for batch %d: %v", i, err)
+		}
+	}
+}
+
+func TestUploadBatchConflictAfterLostResponse(t *testing.T) {
+	wd, _ := os.Getwd()
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	cases := []struct {
+		name     string
+		existing *NodeTableRow // Stored before the upload starts
+		commit   bool          // Whether the first attempt is committed before it fails
+		wantOK   bool
+	}{
+		{"own attempt was committed", nil, true, true},
+		{"node of another user", &NodeTableRow{NodeID: "NodeID-0", BLSPublicKey: "0xaa", BLSSignature: "0xbb", HardwareProviderID: 1, UserID: "other", Network: "fuji"}, false, false},
+		{"node with other data", &NodeTableRow{NodeID: "NodeID-0", BLSPublicKey: "0xcc", BLSSignature: "0xbb", HardwareProviderID: 1, UserID: "user", Network: "fuji"}, false, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var mu sync.Mutex
+			stored := map[string]NodeTableRow{}
+			if c.existing != nil {
+				stored[c.existing.NodeID] = *c.existing
+			}
+			posts := 0
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				if r.Method == http.MethodGet {
+					rows := []NodeTableRow{}
+					for _, row := range stored {
+						rows = append(rows, row)
+					}
+					json.NewEncoder(w).Encode(rows)
+					return
+				}
+				var batch []NodeTableInsertPayload
+				if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				posts++
+				// The first attempt fails at the gateway, after the insert was committed or before it ran.
+				if posts == 1 {
+					if c.commit {
+						for _, p := range batch {
+							stored[p.NodeID] = NodeTableRow{NodeID: p.NodeID, BLSPublicKey: p.BLSPublicKey, BLSSignature: p.BLSSignature,
+								HardwareProviderID: p.HardwareProviderID, UserID: p.UserID, Network: p.Network, L1ID: p.L1ID}
+						}
+					}
+					w.Header().Set("Retry-After", "0")
+					w.WriteHeader(http.StatusGatewayTimeout)
+					return
+				}
+				for _, p := range batch {
+					if _, ok := stored[p.NodeID]; ok {
+						w.WriteHeader(http.StatusConflict)
+						return
+					}
+				}
+				w.WriteHeader(http.StatusCreated)
+			}))
+			defer srv.Close()
+
+			nodes := []models.Node{{NodeID: "NodeID-0", BLSPublicKey: "aa", BLSSignature: "bb"}}
+			args := &UploadArgs{
+				HardwareProviderID: 1,
+				Network:            "fuji",
+				BatchSize:          1,
+				Concurrency:        1,
+				Retries:            2,
+				RequestTimeout:     5 * time.Second,
+				AuthArgs:           AuthArgs{SupabaseURL: srv.URL},
+			}
+			session := &authSession{args: &args.AuthArgs, AccessToken: "token", UserID: "user"}
+
+			err := uploadNodes(args, session, nodes)
+			if c.wantOK && err != nil {
+				t.Fatalf("expected the conflict with the committed attempt to count as success, got %v", err)
+			}
+			if !c.wantOK && err == nil {
+				t.Fatal("expected the conflict with a row that was already there to fail the upload")
+			}
+			if posts != 2 {
+				t.Fatalf("expected 2 attempts, got %d", posts)
+			}
+		})
+	}
+}
+
+func TestUploadBatchRefreshesTokenAfterBackoff(t *testing.T) {
+	isolateConfig(t)
+	wd, _ := os.Getwd()
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	freshToken := testJWT(time.Now().Add(time.Hour))
+	var mu sync.Mutex
+	var bearers []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Path == "/auth/v1/token" {
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"access_token":  freshToken,
+				"refresh_token": "refresh-2",
+				"user":          map[string]string{"id": "user"},
+			})
+			return
+		}
+		bearers = append(bearers, r.Header.Get("Authorization"))
+		if len(bearers) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	nodes := []models.Node{{NodeID: "NodeID-0", BLSPublicKey: "aa", BLSSignature: "bb"}}
+	args := &UploadArgs{
+		HardwareProviderID: 1,
+		Network:            "fuji",
+		BatchSize:          1,
+		Concurrency:        1,
+		Retries:            1,
+		RequestTimeout:     5 * time.Second,
+		AuthArgs:           AuthArgs{SupabaseURL: srv.URL, Profile: defaultProfileName},
+	}
+	// The token is fresh for the first attempt, but within the refresh leeway after the backoff.
+	session := &authSession{
+		args:         &args.AuthArgs,
+		store:        fileStore{},
+		AccessToken:  "old-token",
+		RefreshToken: "refresh-1",
+		UserID:       "user",
+		ExpiresAt:    time.Now().Add(tokenRefreshLeeway + 500*time.Millisecond),
+	}
+
+	if err := uploadNodes(args, session, nodes); err != nil {
+		t.Fatal(err)
+	}
+	if len(bearers) != 2 || bearers[0] != "Bearer old-token" || bearers[1] != "Bearer "+freshToken {
+		t.Fatalf("expected the retry to use the refreshed token, got %v", bearers)
+	}
+}
+
+func TestValidateUploadArgs(t *testing.T) {
+	valid := UploadArgs{BatchSize: 25, Concurrency: 1, RateLimit: 5, Retries: 3, RequestTimeout: 30 * time.Second}
+	if err := validateUploadArgs(&valid); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	cases := map[string]func(a *UploadArgs){
+		"zero batch size":      func(a *UploadArgs) { a.BatchSize = 0 },
+		"zero concurrency":     func(a *UploadArgs) { a.Concurrency = 0 },
+		"negative retries":     func(a *UploadArgs) { a.Retries = -1 },
+		"negative rate limit":  func(a *UploadArgs) { a.RateLimit = -1 },
+		"zero request timeout": func(a *UploadArgs) { a.RequestTimeout = 0 },
+	}
+	for name, change := range cases {
+		args := valid
+		change(&args)
+		if err := validateUploadArgs(&args); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/multisig-labs/tartarus/devserver"
+	"github.com/multisig-labs/tartarus/models"
+	"github.com/multisig-labs/tartarus/node"
+)
+
+// startDevServer runs a devserver with one account, and isolates the config dir and working
+// directory so cached tokens and response files stay inside the test.
+func startDevServer(t *testing.T) (*devserver.Server, *httptest.Server, string) {
+	t.Helper()
+	isolateConfig(t)
+	wd, _ := os.Getwd()
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	dev := devserver.New(devserver.Options{})
+	userID := dev.AddUser("dev@example.com", "dev-password")
+	srv := httptest.NewServer(dev)
+	t.Cleanup(srv.Close)
+	return dev, srv, userID
+}
+
+// writeNodesFile generates n valid nodes and writes them to a nodes JSON file.
+func writeNodesFile(t *testing.T, n int) string {
+	t.Helper()
+	var nodes []models.Node
+	for i := 0; i < n; i++ {
+		generated, err := node.Generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		nodes = append(nodes, generated)
+	}
+	data, err := json.Marshal(map[string][]models.Node{"nodes": nodes})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "nodes.json")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func devUploadArgs(srv *httptest.Server, dataFile string) *UploadArgs {
+	return &UploadArgs{
+		DataFile:           dataFile,
+		HardwareProviderID: 7,
+		Network:            "fuji",
+		BatchSize:          2,
+		Concurrency:        2,
+		Retries:            2,
+		RequestTimeout:     5 * time.Second,
+		AuthArgs: AuthArgs{
+			Email:           "dev@example.com",
+			Password:        "dev-password",
+			SupabaseURL:     srv.URL,
+			SupabaseAnonKey: devserver.DefaultAnonKey,
+			Profile:         defaultProfileName,
+			CredentialStore: credentialStoreFile,
+		},
+	}
+}
+
+func TestUploadEndToEnd(t *testing.T) {
+	dev, srv, userID := startDevServer(t)
+	args := devUploadArgs(srv, writeNodesFile(t, 5))
+	dev.InjectError("POST", nodesTablePath, http.StatusServiceUnavailable, 1)
+
+	session, err := getOrRequestAccessToken(&args.AuthArgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if session.UserID != userID {
+		t.Fatalf("expected user %s, got %s", userID, session.UserID)
+	}
+	if err := uploadNodesToTable(args, session); err != nil {
+		t.Fatal(err)
+	}
+
+	rows := dev.Rows()
+	if len(rows) != 5 {
+		t.Fatalf("expected 5 rows, got %d", len(rows))
+	}
+	for _, r := range rows {
+		if r["user_id"] != userID || r["hardware_provider_id"] != float64(7) || r["network"] != "fuji" {
+			t.Errorf("unexpected row %v", r)
+		}
+	}
+	// 3 batches, one of which was retried after the injected 503.
+	if got := dev.Requests("POST", nodesTablePath); got != 4 {
+		t.Errorf("expected 4 insert requests, got %d", got)
+	}
+
+	// The second run uses the cached token and conflicts on every batch.
+	again, err := getOrRequestAccessToken(&args.AuthArgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := dev.Requests("POST", "/auth/v1/token"); got != 1 {
+		t.Errorf("expected the cached token to be reused, got %d token requests", got)
+	}
+	err = uploadNodesToTable(args, again)
+	if err == nil || err.Error() != "3 of 3 batches failed" {
+		t.Fatalf("expected every batch to conflict, got %v", err)
+	}
+	if len(dev.Rows()) != 5 {
+		t.Fatalf("conflicting upload changed the table: %d rows", len(dev.Rows()))
+	}
+}
+
+func TestUploadEndToEndRenewsExpiredToken(t *testing.T) {
+	dev, srv, _ := startDevServer(t)
+	args := devUploadArgs(srv, writeNodesFile(t, 2))
+
+	session, err := getOrRequestAccessToken(&args.AuthArgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The server rejects the token although its exp claim is still in the future,
+	// so the upload has to react to the 401 rather than refresh up front.
+	dev.ExpireTokens()
+
+	if err := uploadNodesToTable(args, session); err != nil {
+		t.Fatal(err)
+	}
+	if len(dev.Rows()) != 2 {
+		t.Fatalf("expected 2 rows, got %d", len(dev.Rows()))
+	}
+	if got := dev.Requests("POST", "/auth/v1/token"); got != 2 {
+		t.Errorf("expected a login and a refresh, got %d token requests", got)
+	}
+
+	rows, err := newRestClient(session).fetchNodeRows(nil, 1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("expected to list 2 rows, got %d", len(rows))
+	}
+}
+
+func TestGetOrRequestAccessTokenWrongPassword(t *testing.T) {
+	_, srv, _ := startDevServer(t)
+	args := devUploadArgs(srv, "")
+	args.Password = "wrong"
+
+	_, err := getOrRequestAccessToken(&args.AuthArgs)
+	if err == nil || !strings.Contains(err.Error(), "Invalid login credentials") {
+		t.Fatalf("expected invalid credentials, got %v", err)
+	}
+}